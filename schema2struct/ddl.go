@@ -0,0 +1,519 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ddlTable is a table read from a CREATE TABLE statement.
+type ddlTable struct {
+	name    string
+	columns []*column
+	pks     []string
+	serial  map[string]bool
+}
+
+// ddlTypes maps the Postgres type names (and their aliases) that may appear in
+// DDL to the data_type and udt_name that INFORMATION_SCHEMA reports for them.
+//
+// This lets a column read from DDL go through the same type mapping as a
+// column read from a live database.
+var ddlTypes = map[string][2]string{
+	"smallint":                    {"smallint", "int2"},
+	"int2":                        {"smallint", "int2"},
+	"integer":                     {"integer", "int4"},
+	"int":                         {"integer", "int4"},
+	"int4":                        {"integer", "int4"},
+	"bigint":                      {"bigint", "int8"},
+	"int8":                        {"bigint", "int8"},
+	"real":                        {"real", "float4"},
+	"float4":                      {"real", "float4"},
+	"double precision":            {"double precision", "float8"},
+	"float":                       {"double precision", "float8"},
+	"float8":                      {"double precision", "float8"},
+	"numeric":                     {"numeric", "numeric"},
+	"decimal":                     {"numeric", "numeric"},
+	"money":                       {"money", "money"},
+	"text":                        {"text", "text"},
+	"character varying":           {"character varying", "varchar"},
+	"varchar":                     {"character varying", "varchar"},
+	"character":                   {"character", "bpchar"},
+	"char":                        {"character", "bpchar"},
+	"bytea":                       {"bytea", "bytea"},
+	"boolean":                     {"boolean", "bool"},
+	"bool":                        {"boolean", "bool"},
+	"date":                        {"date", "date"},
+	"time":                        {"time without time zone", "time"},
+	"time without time zone":      {"time without time zone", "time"},
+	"time with time zone":         {"time with time zone", "timetz"},
+	"timetz":                      {"time with time zone", "timetz"},
+	"timestamp":                   {"timestamp without time zone", "timestamp"},
+	"timestamp without time zone": {"timestamp without time zone", "timestamp"},
+	"timestamp with time zone":    {"timestamp with time zone", "timestamptz"},
+	"timestamptz":                 {"timestamp with time zone", "timestamptz"},
+	"interval":                    {"interval", "interval"},
+	"uuid":                        {"uuid", "uuid"},
+	"json":                        {"json", "json"},
+	"jsonb":                       {"jsonb", "jsonb"},
+	"inet":                        {"inet", "inet"},
+	"cidr":                        {"cidr", "cidr"},
+	"xml":                         {"xml", "xml"},
+}
+
+// serialTypes maps the SERIAL pseudo-types to the integer type of the column
+// they create.
+var serialTypes = map[string]string{
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+}
+
+// importDDL reads the tables defined in a SQL file and describes their structs.
+//
+// If tables is not empty, only the named tables are returned.
+func importDDL(path string, tables []string, cfg *genConfig) ([]*structDesc, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := parseDDL(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*structDesc{}
+	for _, t := range defs {
+		if len(tables) > 0 && !inList(tables, t.name) {
+			continue
+		}
+		res = append(res, t.structDesc(cfg))
+	}
+	return res, nil
+}
+
+// structDesc renders the table's columns the same way structField does for
+// columns read from the database.
+func (t *ddlTable) structDesc(cfg *genConfig) *structDesc {
+	ff := []fieldDesc{}
+	for _, c := range t.columns {
+		f := pgField(c, t.name, cfg)
+		if inList(t.pks, c.Name) {
+			f.Tag += ",PRIMARY_KEY"
+			f.Key, f.NotNull = true, true
+			if t.serial[c.Name] {
+				f.Tag += ",SERIAL"
+				f.Auto = true
+			}
+		}
+		cfg.defaultComment(&f, c)
+		ff = append(ff, f)
+	}
+	return &structDesc{
+		StructName: cfg.structName(t.name),
+		TableName:  t.name,
+		Fields:     ff,
+		Key:        t.pks,
+	}
+}
+
+// parseDDL finds the CREATE TABLE statements in Postgres DDL and parses them.
+//
+// Statements that cannot change the columns of a table, like CREATE INDEX,
+// are skipped. ALTER TABLE is rejected, since its effect would be lost.
+func parseDDL(src string) ([]*ddlTable, error) {
+	toks, err := lexSQL(src)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []*ddlTable{}
+	for _, stmt := range splitTokens(toks, ";") {
+		if len(stmt) < 2 {
+			continue
+		}
+		if stmt[0].is("ALTER") && stmt[1].is("TABLE") {
+			return nil, fmt.Errorf("ALTER TABLE is not supported, fold the change into CREATE TABLE")
+		}
+		if !stmt[0].is("CREATE") {
+			continue
+		}
+
+		i := 1
+		for i < len(stmt) && (stmt[i].is("TEMP") || stmt[i].is("TEMPORARY") || stmt[i].is("UNLOGGED")) {
+			i++
+		}
+		if i >= len(stmt) || !stmt[i].is("TABLE") {
+			continue
+		}
+		t, err := parseCreateTable(stmt[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// parseCreateTable parses what follows CREATE TABLE.
+func parseCreateTable(toks []sqlToken) (*ddlTable, error) {
+	i := 0
+	if len(toks) > 3 && toks[0].is("IF") && toks[1].is("NOT") && toks[2].is("EXISTS") {
+		i = 3
+	}
+	if i >= len(toks) {
+		return nil, fmt.Errorf("CREATE TABLE has no table name")
+	}
+
+	// Only the table name is kept, as INFORMATION_SCHEMA would report it.
+	name := toks[i].ident()
+	for i += 1; i+1 < len(toks) && toks[i].is("."); i += 2 {
+		name = toks[i+1].ident()
+	}
+
+	if i >= len(toks) || !toks[i].is("(") {
+		return nil, fmt.Errorf("table %s: only CREATE TABLE name (...) is supported", name)
+	}
+	end := closingParen(toks, i)
+	if end < 0 {
+		return nil, fmt.Errorf("table %s: unbalanced parentheses", name)
+	}
+	for _, tk := range toks[end+1:] {
+		if tk.is("INHERITS") {
+			return nil, fmt.Errorf("table %s: INHERITS is not supported", name)
+		}
+	}
+
+	t := &ddlTable{name: name, serial: map[string]bool{}}
+	for _, el := range splitTokens(toks[i+1:end], ",") {
+		if err := t.parseElement(el); err != nil {
+			return nil, fmt.Errorf("table %s: %s", name, err)
+		}
+	}
+	return t, nil
+}
+
+// parseElement parses a column definition or a table constraint.
+func (t *ddlTable) parseElement(el []sqlToken) error {
+	if len(el) == 0 {
+		return nil
+	}
+	if el[0].is("CONSTRAINT") {
+		if len(el) < 3 {
+			return fmt.Errorf("incomplete CONSTRAINT")
+		}
+		el = el[2:]
+	}
+
+	switch {
+	case el[0].is("PRIMARY"):
+		if len(el) < 3 || !el[1].is("KEY") || !el[2].is("(") {
+			return fmt.Errorf("expected PRIMARY KEY (columns)")
+		}
+		end := closingParen(el, 2)
+		if end < 0 {
+			return fmt.Errorf("unbalanced parentheses in PRIMARY KEY")
+		}
+		for _, col := range splitTokens(el[3:end], ",") {
+			if len(col) > 0 {
+				t.pks = append(t.pks, col[0].ident())
+			}
+		}
+		return nil
+	case el[0].is("UNIQUE"), el[0].is("FOREIGN"), el[0].is("CHECK"), el[0].is("EXCLUDE"):
+		return nil
+	case el[0].is("LIKE"):
+		return fmt.Errorf("LIKE is not supported")
+	}
+
+	c := &column{Name: el[0].ident()}
+
+	// The type runs until the first column constraint.
+	end := 1
+	for depth := 0; end < len(el); end++ {
+		if el[end].is("(") {
+			depth++
+		} else if el[end].is(")") {
+			depth--
+		} else if depth == 0 && isColumnConstraint(el[end]) {
+			break
+		}
+	}
+	if end == 1 {
+		return fmt.Errorf("column %s has no type", c.Name)
+	}
+	serial := setDDLType(c, el[1:end])
+
+	for j := end; j < len(el); j++ {
+		switch {
+		case el[j].is("PRIMARY"):
+			t.pks = append(t.pks, c.Name)
+		case el[j].is("NOT") && j+1 < len(el) && el[j+1].is("NULL"):
+			c.NotNull = true
+		case el[j].is("IDENTITY"):
+			serial = true
+		case el[j].is("AS") && j+1 < len(el) && el[j+1].is("("):
+			// GENERATED ALWAYS AS (expression) STORED
+			c.Generated = true
+		case el[j].is("nextval"):
+			serial = true
+		case el[j].is("DEFAULT") && !el[j-1].is("BY"):
+			// GENERATED BY DEFAULT AS IDENTITY has no expression. Otherwise,
+			// the expression runs until the next column constraint.
+			k := j + 1
+			for depth := 0; k < len(el); k++ {
+				if el[k].is("(") {
+					depth++
+				} else if el[k].is(")") {
+					depth--
+				} else if depth == 0 && isColumnConstraint(el[k]) {
+					break
+				}
+			}
+			c.Default = sql.NullString{String: tokensText(el[j+1 : k]), Valid: k > j+1}
+		}
+	}
+	if serial {
+		t.serial[c.Name] = true
+	}
+	t.columns = append(t.columns, c)
+	return nil
+}
+
+// setDDLType sets the type of a column from the tokens that declare it.
+//
+// It returns true if the type is one of the SERIAL pseudo-types.
+func setDDLType(c *column, toks []sqlToken) bool {
+	words, args := []string{}, []string{}
+	array := false
+	depth := 0
+	for _, tk := range toks {
+		switch {
+		case tk.is("("):
+			depth++
+		case tk.is(")"):
+			depth--
+		case depth > 0:
+			if !tk.is(",") {
+				args = append(args, tk.text)
+			}
+		case tk.is("["), tk.is("]"), tk.is("ARRAY"):
+			array = true
+		case tk.is("."):
+			// Drop the schema of a qualified type name.
+			words = words[:0]
+		default:
+			words = append(words, tk.ident())
+		}
+	}
+	base := strings.Join(words, " ")
+
+	serial := false
+	if st, ok := serialTypes[base]; ok {
+		base = st
+		serial = true
+	}
+
+	c.DataType, c.UDTName = "USER-DEFINED", base
+	if tt, ok := ddlTypes[base]; ok {
+		c.DataType, c.UDTName = tt[0], tt[1]
+	}
+	if array {
+		c.DataType, c.UDTName = "ARRAY", "_"+c.UDTName
+	}
+	if len(args) > 0 && (c.DataType == "character varying" || c.DataType == "character") {
+		c.Max, _ = strconv.ParseInt(args[0], 10, 64)
+	}
+	if len(args) > 0 && c.DataType == "numeric" {
+		c.Precision.Int64, _ = strconv.ParseInt(args[0], 10, 64)
+		c.Precision.Valid = true
+		if len(args) > 1 {
+			c.Scale.Int64, _ = strconv.ParseInt(args[1], 10, 64)
+		}
+	}
+	return serial
+}
+
+// isColumnConstraint reports whether a token starts a column constraint.
+func isColumnConstraint(tk sqlToken) bool {
+	for _, kw := range []string{"CONSTRAINT", "NOT", "NULL", "DEFAULT", "PRIMARY", "UNIQUE", "REFERENCES", "CHECK", "COLLATE", "GENERATED"} {
+		if tk.is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokensText writes tokens back as SQL. Words are separated by a space, and
+// punctuation is not, so that the text of now() is now().
+func tokensText(toks []sqlToken) string {
+	out := ""
+	word := false
+	for _, tk := range toks {
+		text := tk.text
+		switch {
+		case tk.literal:
+			text = "'" + strings.Replace(text, "'", "''", -1) + "'"
+		case tk.quoted:
+			text = `"` + strings.Replace(text, `"`, `""`, -1) + `"`
+		}
+		isWord := tk.literal || tk.quoted || isWordByte(tk.text[0])
+		if word && isWord {
+			out += " "
+		}
+		out += text
+		word = isWord
+	}
+	return out
+}
+
+// sqlToken is a lexical token of SQL.
+type sqlToken struct {
+	text string
+	// quoted is set for "quoted" identifiers, which are case sensitive.
+	quoted bool
+	// literal is set for string literals.
+	literal bool
+}
+
+// is reports whether the token is the given keyword or punctuation.
+func (t sqlToken) is(kw string) bool {
+	return !t.quoted && !t.literal && strings.EqualFold(t.text, kw)
+}
+
+// ident returns the token as an identifier, folded to lower case unless quoted.
+func (t sqlToken) ident() string {
+	if t.quoted {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
+
+// lexSQL splits SQL source into tokens, dropping whitespace and comments.
+func lexSQL(src string) ([]sqlToken, error) {
+	toks := []sqlToken{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			var buf []byte
+			j := i + 1
+			for ; ; j++ {
+				if j >= len(src) {
+					return nil, fmt.Errorf("unterminated quote")
+				}
+				if src[j] == c {
+					// A doubled quote is an escaped quote.
+					if j+1 < len(src) && src[j+1] == c {
+						buf = append(buf, c)
+						j++
+						continue
+					}
+					break
+				}
+				buf = append(buf, src[j])
+			}
+			toks = append(toks, sqlToken{text: string(buf), quoted: c == '"', literal: c == '\''})
+			i = j + 1
+		case c == '$' && dollarTag(src[i:]) != "":
+			// Dollar quoted strings, as used by function bodies.
+			tag := dollarTag(src[i:])
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %s quote", tag)
+			}
+			toks = append(toks, sqlToken{text: src[i+len(tag) : i+len(tag)+end], literal: true})
+			i += 2*len(tag) + end
+		case isWordByte(c):
+			j := i
+			for j < len(src) && isWordByte(src[j]) {
+				j++
+			}
+			toks = append(toks, sqlToken{text: src[i:j]})
+			i = j
+		default:
+			toks = append(toks, sqlToken{text: string(c)})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+// dollarTag returns the $tag$ that starts s, or "" if s does not start with one.
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		if s[j] == '$' {
+			return s[:j+1]
+		}
+		if !isWordByte(s[j]) {
+			break
+		}
+	}
+	return ""
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// splitTokens splits tokens on a separator that is not nested in parentheses.
+func splitTokens(toks []sqlToken, sep string) [][]sqlToken {
+	parts := [][]sqlToken{}
+	depth, start := 0, 0
+	for i, tk := range toks {
+		switch {
+		case tk.is("("):
+			depth++
+		case tk.is(")"):
+			depth--
+		case depth == 0 && tk.is(sep):
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
+
+// closingParen returns the index of the parenthesis closing the one at open,
+// or -1 if there is none.
+func closingParen(toks []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(toks); i++ {
+		if toks[i].is("(") {
+			depth++
+		} else if toks[i].is(")") {
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// inList reports whether a list of names contains name.
+func inList(list []string, name string) bool {
+	for _, n := range list {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexSQL(t *testing.T) {
+	tests := []struct {
+		src    string
+		expect []sqlToken
+	}{
+		{"id int", []sqlToken{{text: "id"}, {text: "int"}}},
+		{"varchar(40)", []sqlToken{{text: "varchar"}, {text: "("}, {text: "40"}, {text: ")"}}},
+		{`"Email" text`, []sqlToken{{text: "Email", quoted: true}, {text: "text"}}},
+		{`"say ""hi"""`, []sqlToken{{text: `say "hi"`, quoted: true}}},
+		{"'a;b'", []sqlToken{{text: "a;b", literal: true}}},
+		{"'it''s'", []sqlToken{{text: "it's", literal: true}}},
+		{"a -- comment ;\nb", []sqlToken{{text: "a"}, {text: "b"}}},
+		{"a /* comment ; */ b", []sqlToken{{text: "a"}, {text: "b"}}},
+		{"$$ BEGIN; END; $$", []sqlToken{{text: " BEGIN; END; ", literal: true}}},
+		{"$body$ x $$ y $body$", []sqlToken{{text: " x $$ y ", literal: true}}},
+		{"now()", []sqlToken{{text: "now"}, {text: "("}, {text: ")"}}},
+	}
+	for _, tt := range tests {
+		toks, err := lexSQL(tt.src)
+		if err != nil {
+			t.Errorf("%s: %s", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(toks, tt.expect) {
+			t.Errorf("%s: expected %v, got %v", tt.src, tt.expect, toks)
+		}
+	}
+
+	for _, src := range []string{`"open`, "'open", "/* open", "$tag$ open"} {
+		if _, err := lexSQL(src); err == nil {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+}
+
+func TestSetDDLType(t *testing.T) {
+	tests := []struct {
+		decl              string
+		dataType, udtName string
+		max, prec, scale  int64
+		serial            bool
+	}{
+		{decl: "integer", dataType: "integer", udtName: "int4"},
+		{decl: "int8", dataType: "bigint", udtName: "int8"},
+		{decl: "serial", dataType: "integer", udtName: "int4", serial: true},
+		{decl: "bigserial", dataType: "bigint", udtName: "int8", serial: true},
+		{decl: "character varying(40)", dataType: "character varying", udtName: "varchar", max: 40},
+		{decl: "char(2)", dataType: "character", udtName: "bpchar", max: 2},
+		{decl: "numeric(10, 2)", dataType: "numeric", udtName: "numeric", prec: 10, scale: 2},
+		{decl: "double precision", dataType: "double precision", udtName: "float8"},
+		{decl: "timestamp(3) with time zone", dataType: "timestamp with time zone", udtName: "timestamptz"},
+		{decl: "time", dataType: "time without time zone", udtName: "time"},
+		{decl: "text[]", dataType: "ARRAY", udtName: "_text"},
+		{decl: "integer ARRAY", dataType: "ARRAY", udtName: "_int4"},
+		{decl: "public.mood", dataType: "USER-DEFINED", udtName: "mood"},
+	}
+	for _, tt := range tests {
+		toks, err := lexSQL(tt.decl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &column{}
+		serial := setDDLType(c, toks)
+		if c.DataType != tt.dataType || c.UDTName != tt.udtName || serial != tt.serial {
+			t.Errorf("%s: expected %s (%s), serial %t, got %s (%s), serial %t", tt.decl, tt.dataType, tt.udtName, tt.serial, c.DataType, c.UDTName, serial)
+		}
+		if c.Max != tt.max || c.Precision.Int64 != tt.prec || c.Scale.Int64 != tt.scale {
+			t.Errorf("%s: expected (%d, %d, %d), got (%d, %d, %d)", tt.decl, tt.max, tt.prec, tt.scale, c.Max, c.Precision.Int64, c.Scale.Int64)
+		}
+	}
+}
+
+func TestParseDDL(t *testing.T) {
+	type col struct {
+		name, dataType string
+		notNull        bool
+		def            string
+		generated      bool
+	}
+	tests := []struct {
+		src    string
+		name   string
+		cols   []col
+		pks    []string
+		serial []string
+	}{
+		{
+			src:    "CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL)",
+			name:   "users",
+			cols:   []col{{"id", "integer", false, "", false}, {"name", "text", true, "", false}},
+			pks:    []string{"id"},
+			serial: []string{"id"},
+		},
+		{
+			src:  `CREATE TABLE IF NOT EXISTS public."Users" ("Email" varchar(40))`,
+			name: "Users",
+			cols: []col{{"Email", "character varying", false, "", false}},
+		},
+		{
+			src:  "CREATE UNLOGGED TABLE t (a int DEFAULT 0 NOT NULL, b text DEFAULT 'a;b', c timestamptz DEFAULT now())",
+			name: "t",
+			cols: []col{
+				{"a", "integer", true, "0", false},
+				{"b", "text", false, "'a;b'", false},
+				{"c", "timestamp with time zone", false, "now()", false},
+			},
+		},
+		{
+			src:  "CREATE TABLE t (price numeric, total numeric GENERATED ALWAYS AS (price * 2) STORED)",
+			name: "t",
+			cols: []col{{"price", "numeric", false, "", false}, {"total", "numeric", false, "", true}},
+		},
+		{
+			src:    "CREATE TABLE t (id bigint GENERATED BY DEFAULT AS IDENTITY, PRIMARY KEY (id))",
+			name:   "t",
+			cols:   []col{{"id", "bigint", false, "", false}},
+			pks:    []string{"id"},
+			serial: []string{"id"},
+		},
+		{
+			src:    "CREATE TABLE t (id int DEFAULT nextval('t_id_seq'), org int, CONSTRAINT pk PRIMARY KEY (org, id), UNIQUE (org), CHECK (org > 0))",
+			name:   "t",
+			cols:   []col{{"id", "integer", false, "nextval('t_id_seq')", false}, {"org", "integer", false, "", false}},
+			pks:    []string{"org", "id"},
+			serial: []string{"id"},
+		},
+		{
+			src: `CREATE INDEX i ON x (a);
+CREATE FUNCTION f() RETURNS trigger AS $$ BEGIN; CREATE TABLE y (a int); END; $$ LANGUAGE plpgsql;
+CREATE TABLE x (a int)`,
+			name: "x",
+			cols: []col{{"a", "integer", false, "", false}},
+		},
+	}
+	for _, tt := range tests {
+		tables, err := parseDDL(tt.src)
+		if err != nil {
+			t.Errorf("%s: %s", tt.src, err)
+			continue
+		}
+		if len(tables) != 1 {
+			t.Errorf("%s: expected 1 table, got %d", tt.src, len(tables))
+			continue
+		}
+		tb := tables[0]
+		if tb.name != tt.name {
+			t.Errorf("%s: expected table %s, got %s", tt.src, tt.name, tb.name)
+		}
+		cols := []col{}
+		for _, c := range tb.columns {
+			cols = append(cols, col{c.Name, c.DataType, c.NotNull, c.Default.String, c.Generated})
+		}
+		if !reflect.DeepEqual(cols, tt.cols) {
+			t.Errorf("%s: expected columns %v, got %v", tt.src, tt.cols, cols)
+		}
+		if len(tb.pks) != len(tt.pks) || len(tt.pks) > 0 && !reflect.DeepEqual(tb.pks, tt.pks) {
+			t.Errorf("%s: expected key %v, got %v", tt.src, tt.pks, tb.pks)
+		}
+		serial := []string{}
+		for _, c := range tb.columns {
+			if tb.serial[c.Name] {
+				serial = append(serial, c.Name)
+			}
+		}
+		if len(serial) != len(tt.serial) || len(tt.serial) > 0 && !reflect.DeepEqual(serial, tt.serial) {
+			t.Errorf("%s: expected serial %v, got %v", tt.src, tt.serial, serial)
+		}
+	}
+}
+
+func TestParseDDLErrors(t *testing.T) {
+	for _, src := range []string{
+		"CREATE TABLE x (a int); ALTER TABLE x ADD COLUMN b int",
+		"CREATE TABLE x (a int) INHERITS (y)",
+		"CREATE TABLE x (LIKE y INCLUDING ALL)",
+		"CREATE TABLE x AS SELECT 1",
+		"CREATE TABLE x (a int",
+		"CREATE TABLE x (a)",
+		"CREATE TABLE x (a int DEFAULT 'open)",
+	} {
+		if _, err := parseDDL(src); err == nil {
+			t.Errorf("%s: expected an error", src)
+		}
+	}
+}
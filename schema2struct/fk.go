@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// fkDesc is the column a foreign key column refers to.
+type fkDesc struct {
+	Table, Column string
+}
+
+// fkField is a field for the record a foreign key refers to.
+type fkField struct {
+	Name, Type string
+}
+
+// foreignKeys returns the foreign keys of tables, by table and column.
+//
+// Foreign keys are read from Postgres, MySQL and SQLite. For other databases,
+// none are reported.
+func foreignKeys(tables []string, b squirrel.StatementBuilderType, driver, schema string) (map[string]map[string]*fkDesc, error) {
+	fks := map[string]map[string]*fkDesc{}
+	var q squirrel.SelectBuilder
+	switch driver {
+	case "postgres":
+		q = b.Select("kcu.table_name, kcu.column_name, ref.table_name, ref.column_name").
+			From("INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu").
+			Join("INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc ON rc.constraint_schema = kcu.constraint_schema AND rc.constraint_name = kcu.constraint_name").
+			Join("INFORMATION_SCHEMA.KEY_COLUMN_USAGE ref ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name AND ref.ordinal_position = kcu.position_in_unique_constraint").
+			Where("kcu.table_schema = ?", schema).
+			Where(squirrel.Eq{"kcu.table_name": tables})
+	case "mysql":
+		q = b.Select("table_name, column_name, referenced_table_name, referenced_column_name").
+			From("INFORMATION_SCHEMA.KEY_COLUMN_USAGE").
+			Where("table_schema = DATABASE() AND referenced_table_name IS NOT NULL").
+			Where(squirrel.Eq{"table_name": tables})
+	case "sqlite3":
+		// SQLite lists the foreign keys of one table at a time.
+		for _, tbl := range tables {
+			lit := "'" + strings.Replace(tbl, "'", "''", -1) + "'"
+			q = b.Select(lit + `, "from", "table", "to"`).From("pragma_foreign_key_list(" + lit + ")")
+			if err := scanForeignKeys(q, fks); err != nil {
+				return nil, err
+			}
+		}
+		return fks, nil
+	default:
+		return fks, nil
+	}
+	return fks, scanForeignKeys(q, fks)
+}
+
+// scanForeignKeys adds the foreign keys a query returns to fks. The query
+// selects the table, the column, and the table and column referred to.
+func scanForeignKeys(q squirrel.SelectBuilder, fks map[string]map[string]*fkDesc) error {
+	rows, err := q.Query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tbl, col string
+		fk := &fkDesc{}
+		if err := rows.Scan(&tbl, &col, &fk.Table, &fk.Column); err != nil {
+			return err
+		}
+		if fks[tbl] == nil {
+			fks[tbl] = map[string]*fkDesc{}
+		}
+		fks[tbl][col] = fk
+	}
+	return rows.Err()
+}
+
+// setFKFields adds a field for the referenced record of each foreign key,
+// where the referenced table is generated too.
+//
+// The field is named after the column, so order_id gets an Order field.
+// It is tagged stbl:"-", so that structable ignores it even with a naming
+// strategy, and it is left for the caller to load.
+func setFKFields(descs []*structDesc) {
+	structs := map[string]string{}
+	for _, d := range descs {
+		structs[d.TableName] = d.StructName
+	}
+
+	for _, d := range descs {
+		names := map[string]bool{}
+		for _, f := range d.Fields {
+			names[f.Name] = true
+		}
+		for _, f := range d.Fields {
+			if f.FK == nil || structs[f.FK.Table] == "" {
+				continue
+			}
+			name := goName(strings.TrimSuffix(f.Column, "_id"))
+			if names[name] {
+				name += "Ref"
+			}
+			names[name] = true
+			d.FKFields = append(d.FKFields, fkField{Name: name, Type: structs[f.FK.Table]})
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import "strings"
+
+// irregulars maps plural English words to their singular. Words that
+// singularize wrongly by the rules in singular can be added here.
+var irregulars = map[string]string{
+	"people":      "person",
+	"men":         "man",
+	"women":       "woman",
+	"children":    "child",
+	"mice":        "mouse",
+	"geese":       "goose",
+	"feet":        "foot",
+	"teeth":       "tooth",
+	"indices":     "index",
+	"matrices":    "matrix",
+	"vertices":    "vertex",
+	"criteria":    "criterion",
+	"analyses":    "analysis",
+	"statuses":    "status",
+	"aliases":     "alias",
+	"buses":       "bus",
+	"movies":      "movie",
+	"caches":      "cache",
+	"series":      "series",
+	"species":     "species",
+	"news":        "news",
+	"data":        "data",
+	"information": "information",
+	"equipment":   "equipment",
+}
+
+// singularName returns the struct name for a table, with the last word of
+// the table name made singular. The table user_addresses becomes
+// UserAddress.
+func singularName(tbl string) string {
+	i := strings.LastIndexAny(tbl, "_.") + 1
+	return goName(tbl[:i] + singular(tbl[i:]))
+}
+
+// singular returns the singular of an English plural.
+//
+// It handles the common cases only: categories becomes category, addresses
+// becomes address, and people becomes person. Words that do not look like
+// plurals, such as status, are returned as they are. The case of the word is
+// kept if it is all upper case.
+func singular(word string) string {
+	lower := strings.ToLower(word)
+	res, ok := irregulars[lower]
+	if !ok {
+		res = singularRegular(lower)
+	}
+	if res == lower {
+		return word
+	}
+	if word == strings.ToUpper(word) {
+		return strings.ToUpper(res)
+	}
+	return word[:1] + res[1:]
+}
+
+func singularRegular(w string) string {
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "shes"),
+		strings.HasSuffix(w, "ches"), strings.HasSuffix(w, "xes"),
+		strings.HasSuffix(w, "zzes"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"),
+		strings.HasSuffix(w, "is"):
+		return w
+	case strings.HasSuffix(w, "s") && len(w) > 1:
+		return w[:len(w)-1]
+	}
+	return w
+}
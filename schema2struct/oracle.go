@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Oracle has no INFORMATION_SCHEMA. The tables are read from its data
+// dictionary views instead.
+
+// oracleTables lists the tables of the connected user.
+func oracleTables(b squirrel.StatementBuilderType) ([]string, error) {
+	rows, err := b.Select("table_name").From("USER_TABLES").OrderBy("table_name").Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, oracleName(s))
+	}
+	return res, rows.Err()
+}
+
+// importOracleTable reads a table definition from ALL_TAB_COLUMNS.
+//
+// Identity columns of the primary key are marked AUTO_INCREMENT. Keys filled
+// in by a trigger from a sequence cannot be told apart from plain columns.
+func importOracleTable(tbl string, b squirrel.StatementBuilderType, cfg *genConfig) (*structDesc, error) {
+	pks, err := oraclePrimaryKey(tbl, b)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := b.Select("column_name, data_type, data_scale, nullable, identity_column").
+		From("ALL_TAB_COLUMNS").
+		Where("owner = USER AND table_name = ?", oracleLookup(tbl)).
+		OrderBy("column_id").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ff := []fieldDesc{}
+	for rows.Next() {
+		c := &column{}
+		var scale sql.NullInt64
+		var nullable, identity string
+		if err := rows.Scan(&c.Name, &c.DataType, &scale, &nullable, &identity); err != nil {
+			return nil, err
+		}
+		c.Name = oracleName(c.Name)
+		c.NotNull = nullable == "N"
+
+		tt, ok := lookupType(oracleType(c.DataType, scale))
+		f := fieldDesc{
+			Name:     destutter(goName(c.Name), goName(tbl)),
+			Type:     tt,
+			Column:   c.Name,
+			SQLType:  c.DataType,
+			Tag:      c.Name,
+			NotNull:  c.NotNull,
+			Unmapped: !ok,
+		}
+		if inList(pks, c.Name) {
+			f.Tag += ",PRIMARY_KEY"
+			f.Key = true
+			if identity == "YES" {
+				f.Tag += ",AUTO_INCREMENT"
+				f.Auto = true
+			}
+		}
+		ff = append(ff, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &structDesc{
+		StructName: cfg.structName(tbl),
+		TableName:  tbl,
+		Fields:     ff,
+		Key:        pks,
+	}, nil
+}
+
+// oraclePrimaryKey returns the primary key columns of a table, in order.
+func oraclePrimaryKey(tbl string, b squirrel.StatementBuilderType) ([]string, error) {
+	rows, err := b.Select("cc.column_name").
+		From("ALL_CONSTRAINTS c").
+		Join("ALL_CONS_COLUMNS cc ON cc.owner = c.owner AND cc.constraint_name = c.constraint_name").
+		Where("c.owner = USER AND c.constraint_type = 'P' AND c.table_name = ?", oracleLookup(tbl)).
+		OrderBy("cc.position").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, oracleName(s))
+	}
+	return res, rows.Err()
+}
+
+// oracleType normalizes an Oracle data_type for goType.
+//
+// NUMBER columns with a scale of 0 hold integers, and are reported as
+// INTEGER. The precision of types like TIMESTAMP(6) WITH TIME ZONE is dropped.
+func oracleType(dataType string, scale sql.NullInt64) string {
+	if dataType == "NUMBER" && scale.Valid && scale.Int64 == 0 {
+		return "INTEGER"
+	}
+	if i := strings.Index(dataType, "("); i >= 0 {
+		if j := strings.Index(dataType[i:], ")"); j >= 0 {
+			dataType = dataType[:i] + dataType[i+j+1:]
+		}
+	}
+	return dataType
+}
+
+// oracleName turns a name from the data dictionary into the name used in
+// generated code.
+//
+// Oracle stores unquoted identifiers in upper case, and resolves them in any
+// case, so those are lower cased. Names that were quoted in mixed case are
+// kept as they are.
+func oracleName(name string) string {
+	if name == strings.ToUpper(name) {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// oracleLookup reverses oracleName, giving the name the data dictionary stores.
+func oracleLookup(name string) string {
+	if name == strings.ToLower(name) {
+		return strings.ToUpper(name)
+	}
+	return name
+}
@@ -0,0 +1,9 @@
+// +build oracle
+
+package main
+
+// The Oracle driver needs cgo and the Oracle client libraries, so it is only
+// built with the oracle tag:
+//
+// 	go build -tags oracle
+import _ "github.com/godror/godror"
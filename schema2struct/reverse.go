@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sqlTypes maps Go types to the Postgres types of their columns. It is the
+// reverse of lookupType, and picks one SQL type where lookupType maps
+// several to the same Go type.
+var sqlTypes = map[string]string{
+	"int8":            "smallint",
+	"int16":           "smallint",
+	"int32":           "integer",
+	"int":             "bigint",
+	"int64":           "bigint",
+	"uint8":           "smallint",
+	"uint16":          "integer",
+	"uint32":          "bigint",
+	"float32":         "real",
+	"float64":         "double precision",
+	"bool":            "boolean",
+	"string":          "text",
+	"[]byte":          "bytea",
+	"json.RawMessage": "jsonb",
+	"time.Time":       "timestamp with time zone",
+	"time.Duration":   "interval",
+	"decimal.Decimal": "numeric",
+	"uuid.UUID":       "uuid",
+	"TrimmedString":   "character",
+	"Geometry":        "geometry",
+	"pq.Int64Array":   "bigint[]",
+	"pq.Float64Array": "double precision[]",
+	"pq.BoolArray":    "boolean[]",
+	"pq.StringArray":  "text[]",
+	"pq.ByteaArray":   "bytea[]",
+	// The sql.Null types are nullable columns of their value type.
+	"sql.NullString":      "text",
+	"sql.NullInt16":       "smallint",
+	"sql.NullInt32":       "integer",
+	"sql.NullInt64":       "bigint",
+	"sql.NullFloat64":     "double precision",
+	"sql.NullBool":        "boolean",
+	"sql.NullTime":        "timestamp with time zone",
+	"decimal.NullDecimal": "numeric",
+	"uuid.NullUUID":       "uuid",
+}
+
+// serialOf maps the integer types to the SERIAL pseudo-type of their width.
+var serialOf = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+// goPackage holds the type declarations of a package of Go files.
+type goPackage struct {
+	// names lists the struct types in the order they are declared.
+	names   []string
+	structs map[string]*ast.StructType
+	// named maps the other named types to their underlying type.
+	named map[string]ast.Expr
+	// embedded is set for the struct types embedded in another struct.
+	embedded map[string]bool
+}
+
+// ddlColumn is a column of a table derived from a struct.
+type ddlColumn struct {
+	name, sqlType   string
+	notNull, unique bool
+	key, serial     bool
+	keyOrder        int
+}
+
+// exportDDL reads the structable structs of the Go files in a directory, and
+// returns a CREATE TABLE IF NOT EXISTS statement for each of them, in
+// Postgres syntax.
+//
+// Columns are named by the stbl tags. The table is named by the tablename
+// tag of the generated code, or otherwise after the struct in snake case.
+// Structs embedded in other structs are taken as parts of those, and have
+// no table of their own. If tables is not empty, only the named tables are
+// returned.
+func exportDDL(dir string, tables []string) ([]byte, error) {
+	pkg, err := readGoPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "-- %s\n", strings.TrimPrefix(generatedMark, "// "))
+	for _, name := range pkg.names {
+		if pkg.embedded[name] {
+			continue
+		}
+		table, cols, err := pkg.table(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(cols) == 0 || len(tables) > 0 && !inList(tables, table) {
+			continue
+		}
+		writeCreateTable(out, table, cols)
+	}
+	return out.Bytes(), nil
+}
+
+// readGoPackage parses the Go files of a directory, leaving out the tests.
+func readGoPackage(dir string) (*goPackage, error) {
+	fset := token.NewFileSet()
+	notTest := func(fi os.FileInfo) bool { return !strings.HasSuffix(fi.Name(), "_test.go") }
+	pkgs, err := parser.ParseDir(fset, dir, notTest, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &goPackage{
+		structs:  map[string]*ast.StructType{},
+		named:    map[string]ast.Expr{},
+		embedded: map[string]bool{},
+	}
+	// Map iteration is random, so the files are sorted to keep the order
+	// of the output stable.
+	files := []string{}
+	parsed := map[string]*ast.File{}
+	for _, p := range pkgs {
+		for name, f := range p.Files {
+			files = append(files, name)
+			parsed[name] = f
+		}
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		for _, decl := range parsed[name].Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					pkg.named[ts.Name.Name] = ts.Type
+					continue
+				}
+				pkg.names = append(pkg.names, ts.Name.Name)
+				pkg.structs[ts.Name.Name] = st
+				for _, f := range st.Fields.List {
+					if id, ok := f.Type.(*ast.Ident); ok && len(f.Names) == 0 {
+						pkg.embedded[id.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return pkg, nil
+}
+
+// table returns the name and the columns of the table of a struct.
+func (pkg *goPackage) table(name string) (string, []*ddlColumn, error) {
+	table := snakeName(name)
+	cols := []*ddlColumn{}
+	var add func(st *ast.StructType) error
+	add = func(st *ast.StructType) error {
+		for _, f := range st.Fields.List {
+			tag := fieldTag(f)
+			if tn := tag.Get("tablename"); tn != "" {
+				table = tn
+			}
+			stbl := tag.Get("stbl")
+			if stbl == "-" {
+				continue
+			}
+			if stbl == "" {
+				// structable reads the fields of untagged embedded structs.
+				if id, ok := f.Type.(*ast.Ident); ok && len(f.Names) == 0 && pkg.structs[id.Name] != nil {
+					if err := add(pkg.structs[id.Name]); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			c, err := pkg.column(f.Type, stbl)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			cols = append(cols, c)
+		}
+		return nil
+	}
+	err := add(pkg.structs[name])
+	return table, cols, err
+}
+
+// column describes the column of a field, from its type and stbl tag.
+func (pkg *goPackage) column(typ ast.Expr, stbl string) (*ddlColumn, error) {
+	parts := strings.Split(stbl, ",")
+	c := &ddlColumn{name: strings.TrimSpace(parts[0])}
+	for _, part := range parts[1:] {
+		switch part = strings.TrimSpace(part); part {
+		case "PRIMARY_KEY", "PRIMARY KEY":
+			c.key = true
+		case "AUTO_INCREMENT", "SERIAL", "AUTO INCREMENT":
+			c.serial = true
+		case "UNIQUE":
+			c.unique = true
+		default:
+			if strings.HasPrefix(part, "KEY_ORDER=") {
+				c.keyOrder, _ = strconv.Atoi(strings.TrimPrefix(part, "KEY_ORDER="))
+			}
+		}
+	}
+
+	goType := typeString(typ)
+	nullable := strings.HasPrefix(goType, "*") || strings.Contains(goType, ".Null") ||
+		strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "pq.")
+	goType = strings.TrimPrefix(goType, "*")
+	// Types declared in the package, like the enum types, are stored as
+	// their underlying type.
+	for i := 0; i < 10 && sqlTypes[goType] == "" && pkg.named[goType] != nil; i++ {
+		goType = typeString(pkg.named[goType])
+	}
+	sqlType, ok := sqlTypes[goType]
+	if !ok {
+		return nil, fmt.Errorf("column %s has type %s, which has no SQL type", c.name, goType)
+	}
+	c.sqlType = sqlType
+	if c.serial {
+		if c.sqlType = serialOf[sqlType]; c.sqlType == "" {
+			return nil, fmt.Errorf("column %s is SERIAL, but has type %s", c.name, goType)
+		}
+	}
+	c.notNull = !nullable && !c.key
+	return c, nil
+}
+
+// writeCreateTable writes the CREATE TABLE statement of a table.
+func writeCreateTable(out *bytes.Buffer, table string, cols []*ddlColumn) {
+	fmt.Fprintf(out, "\nCREATE TABLE IF NOT EXISTS %s (\n", table)
+	keys := []*ddlColumn{}
+	lines := []string{}
+	for _, c := range cols {
+		line := "\t" + c.name + " " + c.sqlType
+		if c.notNull {
+			line += " NOT NULL"
+		}
+		if c.unique {
+			line += " UNIQUE"
+		}
+		lines = append(lines, line)
+		if c.key {
+			keys = append(keys, c)
+		}
+	}
+	if len(keys) > 0 {
+		// Key columns without KEY_ORDER follow those with it, in field
+		// order, as structable orders them.
+		sort.SliceStable(keys, func(i, j int) bool {
+			if (keys[i].keyOrder == 0) != (keys[j].keyOrder == 0) {
+				return keys[j].keyOrder == 0
+			}
+			return keys[i].keyOrder < keys[j].keyOrder
+		})
+		names := []string{}
+		for _, k := range keys {
+			names = append(names, k.name)
+		}
+		lines = append(lines, "\tPRIMARY KEY ("+strings.Join(names, ", ")+")")
+	}
+	fmt.Fprintf(out, "%s\n);\n", strings.Join(lines, ",\n"))
+}
+
+// fieldTag returns the tag of a struct field.
+func fieldTag(f *ast.Field) reflect.StructTag {
+	if f.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag)
+}
+
+// typeString returns a type as it is written in Go source, like *time.Time.
+func typeString(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + typeString(t.Elt)
+		}
+	}
+	return fmt.Sprintf("%T", typ)
+}
+
+// snakeName converts a Go name to a SQL name, so that UserAddress becomes
+// user_address.
+func snakeName(goName string) string {
+	runes := []rune(goName)
+	out := []rune{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A new word starts at an upper case letter, unless it
+			// continues an acronym, like the ID of UserID.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
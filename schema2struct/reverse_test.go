@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestExportDDL(t *testing.T) {
+	out, err := exportDDL("testdata/reverse", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect, err := ioutil.ReadFile("testdata/reverse.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expect) {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expect, out)
+	}
+
+	out, err = exportDDL("testdata/reverse", []string{"event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("accounts")) || !bytes.Contains(out, []byte("CREATE TABLE IF NOT EXISTS event (")) {
+		t.Errorf("Expected only the event table, got:\n%s", out)
+	}
+}
+
+func TestSnakeName(t *testing.T) {
+	for goName, expect := range map[string]string{
+		"User":        "user",
+		"UserAddress": "user_address",
+		"UserID":      "user_id",
+		"HTTPStatus":  "http_status",
+	} {
+		if got := snakeName(goName); got != expect {
+			t.Errorf("Expected %s for %s, got %s", expect, goName, got)
+		}
+	}
+}
@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"go/format"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/codegangsta/cli"
@@ -24,41 +32,183 @@ This utility generates Structable structs be reading your database table and
 generating the appropriate code.
 `
 
-const fileHeader = `package %s
+const fileHeader = `package {{.Package}}
 
-// This file is automatically generated by schema2struct.
+` + generatedMark + `
 
 import (
-	"time"
-
-	"github.com/Masterminds/squirrel"
-	"github.com/Masterminds/structable"
-	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
-)
-
+{{range .StdImports}}	{{.}}
+{{end}}
+{{range .PkgImports}}	{{.}}
+{{end}})
+{{if not .GORM}}
 // QueryFunc modifies a SelectBuilder prior to execution.
 //
 // The SelectBuilder is modified in place. An error is returned under any
 // conditions where the query should not be executed.
 type QueryFunc func(q squirrel.SelectBuilder) (squirrel.SelectBuilder, error)
+{{end}}{{if .DBInterface}}
+// {{.DBInterface}} describes the database handle the generated code uses.
+//
+// It declares exactly the methods structable needs, so a mock can stand in
+// for the database in tests.
+type {{.DBInterface}} interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) squirrel.RowScanner
+	Prepare(query string) (*sql.Stmt, error)
+	Begin() (*sql.Tx, error)
+}
+{{end}}{{if .PostGIS}}
+// Geometry holds a PostGIS geometry or geography value.
+//
+// PostGIS transfers geometries as hex encoded EWKB. Geometry stores the
+// decoded EWKB bytes, which any WKB library can parse.
+type Geometry []byte
+
+// Scan implements sql.Scanner.
+func (g *Geometry) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*g = nil
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into Geometry", src)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	*g = b
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (g Geometry) Value() (driver.Value, error) {
+	if g == nil {
+		return nil, nil
+	}
+	return hex.EncodeToString(g), nil
+}
+{{end}}{{if .TrimChar}}
+// TrimmedString holds a char(n) value without its padding.
+//
+// Postgres pads char(n) values with spaces up to n characters. TrimmedString
+// drops the trailing spaces when scanning, and the database pads the value
+// again when it is stored.
+type TrimmedString string
+
+// Scan implements sql.Scanner.
+func (s *TrimmedString) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s = ""
+	case []byte:
+		*s = TrimmedString(strings.TrimRight(string(v), " "))
+	case string:
+		*s = TrimmedString(strings.TrimRight(v, " "))
+	default:
+		return fmt.Errorf("cannot scan %T into TrimmedString", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (s TrimmedString) Value() (driver.Value, error) {
+	return string(s), nil
+}
+{{end}}{{range .Enums}}{{$enum := .Name}}
+// {{.Name}} holds a value of the Postgres enum {{.Type}}.
+type {{.Name}} string
+
+// The values of {{.Name}}.
+const (
+{{range .Values}}	{{.Name}} {{$enum}} = {{printf "%q" .Label}}
+{{end}})
+
+// Scan implements sql.Scanner.
+func (e *{{.Name}}) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*e = ""
+	case []byte:
+		*e = {{.Name}}(v)
+	case string:
+		*e = {{.Name}}(v)
+	default:
+		return fmt.Errorf("cannot scan %T into {{.Name}}", src)
+	}
+	return nil
+}
 
+// Value implements driver.Valuer.
+func (e {{.Name}}) Value() (driver.Value, error) {
+	return string(e), nil
+}
+{{end}}
 `
 
-const structTemplate = `// {{.StructName}} maps to database table {{.TableName}}
-type {{.StructName}} struct {
+const structTemplate = `// {{.StructName}}Table is the name of the table {{.StructName}} maps to.
+const {{.StructName}}Table = "{{.TableName}}"
+
+// {{.StructName}} maps to database table {{.TableName}}
+{{if .Comment}}//
+{{comment .Comment}}
+{{end}}{{if .View}}//
+// View: read-only
+{{end}}{{if .NoKey}}//
+// WARNING: no primary key; Load/Update/Delete unavailable
+{{end}}{{if .CompositeKey}}//
+// The primary key is composite, in the order ({{.CompositeKey}}).
+{{end}}{{if .UniqueGroups}}//
+{{range .UniqueGroups}}// The columns ({{.}}) are unique together.
+{{end}}{{end}}type {{.StructName}} struct {
+	{{range .Fields}}{{if .FK}}// FK: {{.Column}} -> {{.FK.Table}}({{.FK.Column}})
+	{{end}}{{if .Comment}}{{comment .Comment}}
+	{{end}}{{.}}{{if $.TypeComments}} // {{.SQLType}}{{end}}
+	{{end}}{{if .FKFields}}// Records the foreign keys refer to. They are not loaded by structable.
+	{{end}}{{range .FKFields}}{{.Name}} *{{.Type}} {{ann "stbl" "-"}}
+	{{end}}
 	tableName string {{ann "tablename" .TableName}}
 	structable.Recorder
 	builder squirrel.StatementBuilderType
-	{{range .Fields}}{{.}}
-	{{end}}db squirrel.DBProxyBeginner
-	flavor string
+	db      {{.DBType}}
+	flavor  string
+}
+
+{{if .Relations}}// {{.StructName}}Relations lists the tables with rows that refer to {{.StructName}}.
+//
+// Pass it to DeleteCascade to delete those rows together with a {{.StructName}}.
+var {{.StructName}}Relations = []structable.Relation{
+	{{range .Relations}}{Table: "{{.Table}}", Column: "{{.Column}}"},
+	{{end}}
+}
+
+{{end}}// {{.StructName}}Columns returns the columns of {{.StructName}}Table, in table order.
+func {{.StructName}}Columns() []string {
+	return []string{ {{range $i, $f := .Fields}}{{if $i}}, {{end}}"{{$f.Column}}"{{end}} }
 }
 
 // New{{.StructName}} creates a new {{.StructName}} wired to structable.
-func New{{.StructName}}(db squirrel.DBProxyBeginner, flavor string) *{{.StructName}} {
-	o := &{{.StructName}}{db: db, flavor: flavor}
-	o.Recorder = structable.New(db, flavor).Bind("{{.TableName}}", o)
+func New{{.StructName}}(db {{.DBType}}, flavor string) *{{.StructName}} {
+	o := Bind{{.StructName}}(structable.New(db, flavor))
+	o.db, o.flavor = db, flavor
+	return o
+}
+
+// Bind{{.StructName}} creates a new {{.StructName}} wired to an existing recorder,
+// such as one created on a transaction.
+//
+// The recorder is bound to the new {{.StructName}}, so each object needs a
+// recorder of its own.
+func Bind{{.StructName}}(rec structable.Recorder) *{{.StructName}} {
+	o := &{{.StructName}}{}
+	o.Recorder = rec.Bind({{.StructName}}Table, o)
 	return o
 }
 
@@ -66,7 +216,7 @@ func New{{.StructName}}(db squirrel.DBProxyBeginner, flavor string) *{{.StructNa
 //
 // Limit is the max number of items. Offset is the offset the results will
 // begin with.
-func List{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, limit, offset uint64) ([]*{{.StructName}}, error) {
+func List{{.StructName}}(db {{.DBType}}, flavor string, limit, offset uint64) ([]*{{.StructName}}, error) {
 	fn := func(q squirrel.SelectBuilder) (squirrel.SelectBuilder, error) {
 		return q.Limit(limit).Offset(offset), nil
 	}
@@ -83,8 +233,8 @@ func List{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, limit, offs
 // The QueryFunc should not modify the list of fields returned or the table name,
 // as the intent is to construct a complete {{.StructName}} from each result.
 // More sophisticated queries should be written directly.
-func Query{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, fn QueryFunc) ([]*{{.StructName}}, error){
-	var tn string = "{{.TableName}}"
+func Query{{.StructName}}(db {{.DBType}}, flavor string, fn QueryFunc) ([]*{{.StructName}}, error){
+	var tn string = {{.StructName}}Table
 
 	// We need a prototype structable to learn about the table structure.
 	ps := New{{.StructName}}(db, flavor)
@@ -114,7 +264,7 @@ func Query{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, fn QueryFu
 }
 
 // Len{{.StructName}} returns the number of {{.StructName}} objects in the database.
-func Len{{.StructName}}(db squirrel.DBProxyBeginner, flavor string) (int, error) {
+func Len{{.StructName}}(db {{.DBType}}, flavor string) (int, error) {
 	fn := func(q squirrel.SelectBuilder) (squirrel.SelectBuilder, error) {return q, nil}
 	return QueryLen{{.StructName}}(db, flavor, fn)
 }
@@ -123,8 +273,8 @@ func Len{{.StructName}}(db squirrel.DBProxyBeginner, flavor string) (int, error)
 //
 // The QueryFunc can be used to modify the query. For a simple length call, you
 // may prefer to use Len{{.StructName}}.
-func QueryLen{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, fn QueryFunc) (int, error) {
-	tn := "{{.TableName}}"
+func QueryLen{{.StructName}}(db {{.DBType}}, flavor string, fn QueryFunc) (int, error) {
+	tn := {{.StructName}}Table
 	ps := New{{.StructName}}(db, flavor)
 	q := ps.Builder().Select("COUNT(*)").From(tn)
 	var err error
@@ -135,13 +285,362 @@ func QueryLen{{.StructName}}(db squirrel.DBProxyBeginner, flavor string, fn Quer
 	err = q.Scan(&count)
 	return count, err
 }
+{{if and .Copy (not .View)}}
+// CopyFrom{{.StructName}} inserts many {{.StructName}} objects using the Postgres COPY protocol.
+//
+// COPY is much faster than INSERT for large loads. All records are written
+// in one transaction. Columns the database fills in (SERIAL) are not written.
+func CopyFrom{{.StructName}}(db {{.DBType}}, records []*{{.StructName}}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare({{if .CopySchema}}pq.CopyInSchema("{{.CopySchema}}", "{{.CopyTable}}"{{else}}pq.CopyIn({{.StructName}}Table{{end}}{{range .CopyFields}}, "{{.Column}}"{{end}}))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, o := range records {
+		if _, err := stmt.Exec({{range $i, $f := .CopyFields}}{{if $i}}, {{end}}o.{{$f.Name}}{{end}}); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	// An Exec without arguments flushes the buffered rows.
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		tx.Rollback()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+{{end}}{{if .Maps}}
+// ToMap returns the fields of the {{.StructName}}, keyed by column name.
+func (o *{{.StructName}}) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		{{range .Fields}}"{{.Column}}": o.{{.Name}},
+		{{end}}
+	}
+}
+
+// FromMap sets the fields of the {{.StructName}} from a map keyed by column name.
+//
+// Fields whose column is not in the map are left as they are. A nil value
+// sets the zero value, and a value of another type than its field is an error.
+func (o *{{.StructName}}) FromMap(m map[string]interface{}) error {
+	{{range .Fields}}if v, ok := m["{{.Column}}"]; ok {
+		x, ok := v.({{.Type}})
+		if !ok && v != nil {
+			return fmt.Errorf("{{.Column}} is a %T, not a {{.Type}}", v)
+		}
+		o.{{.Name}} = x
+	}
+	{{end}}return nil
+}
+{{end}}{{if .Stringer}}
+// String implements fmt.Stringer. It shows only the columns that identify
+// the {{.StructName}}, so that logging it stays short.
+func (o {{.StructName}}) String() string {
+	return fmt.Sprintf({{.StringFormat}}{{range .StringFields}}, o.{{.Name}}{{end}})
+}
+{{end}}
+`
+
+// gormTemplate describes a table for GORM instead of structable.
+const gormTemplate = `// {{.StructName}}Table is the name of the table {{.StructName}} maps to.
+const {{.StructName}}Table = "{{.TableName}}"
+
+// {{.StructName}} maps to database table {{.TableName}}
+{{if .Comment}}//
+{{comment .Comment}}
+{{end}}{{if .View}}//
+// View: read-only
+{{end}}{{if .CompositeKey}}//
+// The primary key is composite, in the order ({{.CompositeKey}}).
+{{end}}{{if .UniqueGroups}}//
+{{range .UniqueGroups}}// The columns ({{.}}) are unique together.
+{{end}}{{end}}type {{.StructName}} struct {
+	{{range .Fields}}{{if .FK}}// FK: {{.Column}} -> {{.FK.Table}}({{.FK.Column}})
+	{{end}}{{if .Comment}}{{comment .Comment}}
+	{{end}}{{.GORM}}{{if $.TypeComments}} // {{.SQLType}}{{end}}
+	{{end}}
+}
 
+// TableName tells GORM which table {{.StructName}} maps to.
+func ({{.StructName}}) TableName() string {
+	return {{.StructName}}Table
+}
+{{if .Stringer}}
+// String implements fmt.Stringer. It shows only the columns that identify
+// the {{.StructName}}, so that logging it stays short.
+func (o {{.StructName}}) String() string {
+	return fmt.Sprintf({{.StringFormat}}{{range .StringFields}}, o.{{.Name}}{{end}})
+}
+{{end}}
 `
 
+type headerDesc struct {
+	Package     string
+	DBInterface string
+	PostGIS     bool
+	TrimChar    bool
+	Copy        bool
+	Maps        bool
+	Stringer    bool
+	GORM        bool
+	Time        bool
+	SQL         bool
+	PQ          bool
+	JSON        bool
+	Decimal     bool
+	UUID        bool
+	// Enums are the enum types the fields use.
+	Enums []*enumDesc
+	// Driver is the database driver, whose package is imported for its
+	// side effect of registering with database/sql.
+	Driver string
+}
+
+// StdImports lists the standard library imports the generated code uses.
+func (hd *headerDesc) StdImports() []string {
+	imports := []string{}
+	add := func(pkg string, used bool) {
+		if used {
+			imports = append(imports, strconv.Quote(pkg))
+		}
+	}
+	add("database/sql", hd.DBInterface != "" || hd.SQL)
+	add("database/sql/driver", hd.PostGIS || hd.TrimChar || len(hd.Enums) > 0)
+	add("encoding/hex", hd.PostGIS)
+	add("encoding/json", hd.JSON)
+	add("fmt", hd.PostGIS || hd.TrimChar || len(hd.Enums) > 0 || hd.Maps && !hd.GORM || hd.Stringer)
+	add("strings", hd.TrimChar)
+	add("time", hd.Time)
+	return imports
+}
+
+// PkgImports lists the third party imports the generated code uses.
+func (hd *headerDesc) PkgImports() []string {
+	pq := hd.PQ || hd.Copy && !hd.GORM
+	imports := []string{}
+	if !hd.GORM {
+		imports = append(imports, `"github.com/Masterminds/squirrel"`, `"github.com/Masterminds/structable"`)
+	}
+	if pq {
+		imports = append(imports, `"github.com/lib/pq"`)
+	}
+	if hd.Decimal {
+		imports = append(imports, `"github.com/shopspring/decimal"`)
+	}
+	if hd.UUID {
+		imports = append(imports, `"github.com/google/uuid"`)
+	}
+	if pkg, ok := driverPackages[hd.Driver]; ok && !hd.GORM && !(pq && pkg == "github.com/lib/pq") {
+		imports = append(imports, `_ "`+pkg+`"`)
+	}
+	return imports
+}
+
+// enumDesc describes the Go type generated for a Postgres enum.
+type enumDesc struct {
+	// Name is the Go type, and Type the enum type in the database.
+	Name, Type string
+	Values     []enumValue
+}
+
+// enumValue is a label of an enum, with the name of its constant.
+type enumValue struct {
+	Name, Label string
+}
+
+// driverPackages maps each driver to the package that registers it.
+var driverPackages = map[string]string{
+	"postgres": "github.com/lib/pq",
+	"mysql":    "github.com/go-sql-driver/mysql",
+	"sqlite3":  "github.com/mattn/go-sqlite3",
+	"oracle":   "github.com/godror/godror",
+}
+
 type structDesc struct {
 	StructName string
 	TableName  string
-	Fields     []string
+	Fields     []fieldDesc
+	// Key lists the primary key columns in key order.
+	Key       []string
+	DBType    string
+	Relations []relationDesc
+	FKFields  []fkField
+	Copy      bool
+	// Maps adds ToMap and FromMap methods.
+	Maps bool
+	// Stringer adds a String method.
+	Stringer bool
+	// TypeComments adds the SQL type of each column as a comment.
+	TypeComments bool
+	// View is set for views, which have no key and cannot be written.
+	View bool
+	// Comment is the comment on the table, written above the struct.
+	Comment string
+	// Unique lists the columns of each unique constraint, in order.
+	Unique [][]string
+}
+
+// CompositeKey returns the columns of a composite primary key, comma
+// separated, or nothing if the key has a single column.
+func (s *structDesc) CompositeKey() string {
+	if len(s.Key) < 2 {
+		return ""
+	}
+	return strings.Join(s.Key, ", ")
+}
+
+// NoKey reports whether a table has no primary key. Structable cannot load,
+// update or delete its rows one by one. Views never have a key, and are
+// read-only anyway.
+func (s *structDesc) NoKey() bool {
+	return len(s.Key) == 0 && !s.View
+}
+
+// UniqueGroups returns the columns of each unique constraint on more than
+// one column, comma separated.
+func (s *structDesc) UniqueGroups() []string {
+	groups := []string{}
+	for _, u := range s.Unique {
+		if len(u) > 1 {
+			groups = append(groups, strings.Join(u, ", "))
+		}
+	}
+	return groups
+}
+
+// CopyFields returns the fields a COPY writes, leaving out the columns the
+// database fills in.
+func (s *structDesc) CopyFields() []fieldDesc {
+	ff := []fieldDesc{}
+	for _, f := range s.Fields {
+		if !f.Auto {
+			ff = append(ff, f)
+		}
+	}
+	return ff
+}
+
+// CopySchema returns the schema of a qualified table name, or nothing.
+// COPY into a qualified table needs pq.CopyInSchema, since pq.CopyIn quotes
+// the whole name as one identifier.
+func (s *structDesc) CopySchema() string {
+	if i := strings.Index(s.TableName, "."); i >= 0 {
+		return s.TableName[:i]
+	}
+	return ""
+}
+
+// CopyTable returns the table name without its schema.
+func (s *structDesc) CopyTable() string {
+	return s.TableName[strings.Index(s.TableName, ".")+1:]
+}
+
+// StringFields returns the fields that String shows: the primary key, or
+// else the columns that are unique on their own, or else the first column.
+// Columns that may be NULL are left out, as their values print badly.
+func (s *structDesc) StringFields() []fieldDesc {
+	key, unique := []fieldDesc{}, []fieldDesc{}
+	for _, f := range s.Fields {
+		switch {
+		case f.Key:
+			key = append(key, f)
+		case f.Unique && f.NotNull:
+			unique = append(unique, f)
+		}
+	}
+	if len(key) > 0 {
+		return key
+	}
+	if len(unique) > 0 {
+		return unique
+	}
+	for _, f := range s.Fields {
+		if f.NotNull {
+			return []fieldDesc{f}
+		}
+	}
+	return nil
+}
+
+// StringFormat returns the quoted format of String, like "User{id=%v}".
+func (s *structDesc) StringFormat() string {
+	cols := []string{}
+	for _, f := range s.StringFields() {
+		cols = append(cols, strings.Replace(f.Column, "%", "%%", -1)+"=%v")
+	}
+	return strconv.Quote(s.StructName + "{" + strings.Join(cols, ", ") + "}")
+}
+
+// fieldDesc describes the struct field generated for a column.
+type fieldDesc struct {
+	Name, Type string
+	Column     string
+	// SQLType is the type of the column as the database names it.
+	SQLType string
+	// JSON is the content of the json tag: the key, and possibly
+	// omitempty. There is no json tag if it is empty.
+	JSON string
+	// FK is the column this column refers to, if it is a foreign key.
+	FK *fkDesc
+	// Unmapped is set if the SQL type has no Go type, and became a string.
+	Unmapped bool
+	// Comment is written on the line above the field.
+	Comment string
+	// Tag is the content of the stbl tag.
+	Tag string
+	// Key is set for primary key columns.
+	Key bool
+	// Auto is set for SERIAL and AUTO_INCREMENT columns.
+	Auto    bool
+	NotNull bool
+	// Unique is set for columns with a unique constraint of their own.
+	Unique bool
+}
+
+// String renders the field declaration.
+func (f fieldDesc) String() string {
+	return fmt.Sprintf("%s %s `stbl:\"%s\"%s`", f.Name, f.Type, f.Tag, f.jsonTag())
+}
+
+// jsonTag returns the json tag, with a leading space, or nothing.
+func (f fieldDesc) jsonTag() string {
+	if f.JSON == "" {
+		return ""
+	}
+	return fmt.Sprintf(" json:\"%s\"", f.JSON)
+}
+
+// GORM renders the field declaration with a gorm tag in place of the stbl tag.
+func (f fieldDesc) GORM() string {
+	tag := "column:" + f.Column
+	if f.Key {
+		tag += ";primaryKey"
+	}
+	if f.Auto {
+		tag += ";autoIncrement"
+	}
+	if f.NotNull {
+		tag += ";not null"
+	}
+	if f.Unique {
+		tag += ";unique"
+	}
+	return fmt.Sprintf("%s %s `gorm:\"%s\"%s`", f.Name, f.Type, tag, f.jsonTag())
+}
+
+// relationDesc is a column of another table that refers to a struct's table.
+type relationDesc struct {
+	Table, Column string
 }
 
 func main() {
@@ -154,7 +653,7 @@ func main() {
 		cli.StringFlag{
 			Name:  "driver,d",
 			Value: "postgres",
-			Usage: "The name of the SQL driver to use.",
+			Usage: "The name of the SQL driver to use: postgres, mysql, sqlite3 or oracle. The case does not matter, and postgresql and sqlite are accepted too.",
 		},
 		cli.StringFlag{
 			Name:  "connection,c",
@@ -167,9 +666,38 @@ func main() {
 			Usage: "The list of tables to generate, comma separated. If none specified, the entire schema is used.",
 		},
 		cli.StringFlag{
-			Name:  "file,f",
+			Name:  "schema,s",
+			Value: "public",
+			Usage: "The Postgres schemas to read tables from, comma separated. Tables outside public are named with their schema, like billing.invoices.",
+		},
+		cli.BoolFlag{
+			Name:  "schema-prefix",
+			Usage: "Start struct names with the schema of their table, so billing.invoices becomes BillingInvoices.",
+		},
+		cli.StringFlag{
+			Name:  "file,f,out,o",
 			Value: "",
-			Usage: "The file to send the output.",
+			Usage: "The file to send the output. Missing directories are created. If empty, the output goes to stdout.",
+		},
+		cli.BoolFlag{
+			Name:  "soft-delete",
+			Usage: "Tag nullable deleted_at timestamp columns with SOFT_DELETE, so that Delete only sets them.",
+		},
+		cli.BoolFlag{
+			Name:  "require-pk",
+			Usage: "Skip the tables without a primary key, instead of generating structs that warn about it.",
+		},
+		cli.BoolFlag{
+			Name:  "skip-views",
+			Usage: "Do not generate structs for views. Views are otherwise generated without a primary key.",
+		},
+		cli.BoolFlag{
+			Name:  "list",
+			Usage: "List the tables that would be generated, with their primary keys, and generate nothing.",
+		},
+		cli.BoolFlag{
+			Name:  "force",
+			Usage: "Replace the output file even if schema2struct did not generate it.",
 		},
 		cli.StringFlag{
 			Name:   "package,p",
@@ -177,194 +705,1201 @@ func main() {
 			Usage:  "The name of the destination package.",
 			EnvVar: "GOPACKAGE",
 		},
+		cli.StringFlag{
+			Name:  "db-interface",
+			Value: "",
+			Usage: "Declare a database interface with this name and use it in place of squirrel.DBProxyBeginner.",
+		},
+		cli.BoolFlag{
+			Name:  "gorm",
+			Usage: "Generate GORM models with gorm tags instead of structable code. Flags for structable code are ignored.",
+		},
+		cli.BoolFlag{
+			Name:  "json",
+			Usage: "Add a json tag to each field, keyed by the column name.",
+		},
+		cli.StringFlag{
+			Name:  "decimal-type",
+			Value: "",
+			Usage: "Set to shopspring to generate github.com/shopspring/decimal fields for numeric and decimal columns, instead of strings.",
+		},
+		cli.StringFlag{
+			Name:  "uuid-type",
+			Value: "string",
+			Usage: "The type of uuid columns, string or google, for github.com/google/uuid.",
+		},
+		cli.BoolFlag{
+			Name:  "json-bytes",
+			Usage: "Generate []byte fields for json and jsonb columns, instead of json.RawMessage.",
+		},
+		cli.StringFlag{
+			Name:  "json-case",
+			Value: "column",
+			Usage: "The case of the json keys: column (the column name as it is) or camel.",
+		},
+		cli.BoolFlag{
+			Name:  "json-omitempty",
+			Usage: "Add omitempty to the json tags of nullable columns, so that NULL values are left out.",
+		},
+		cli.BoolFlag{
+			Name:  "fk-fields",
+			Usage: "For each foreign key to a generated table, add a pointer field for the referenced record, tagged stbl:\"-\".",
+		},
+		cli.BoolFlag{
+			Name:  "singular",
+			Usage: "Name structs in the singular, so the table users becomes the struct User.",
+		},
+		cli.BoolFlag{
+			Name:  "enums",
+			Usage: "Generate a string type with a constant for each value of the Postgres enum types, and use it for their columns.",
+		},
+		cli.StringFlag{
+			Name:  "strip-prefix",
+			Value: "",
+			Usage: "Table name prefixes to leave out of struct names, comma separated, like tbl_. The first that matches is removed.",
+		},
+		cli.StringFlag{
+			Name:  "type-map",
+			Value: "",
+			Usage: "A JSON file mapping SQL types to Go types, like {\"citext\": \"string\"}. Its entries take precedence.",
+		},
+		cli.BoolFlag{
+			Name:  "strict",
+			Usage: "Fail on columns with a SQL type that has no Go type, rather than using string.",
+		},
+		cli.StringFlag{
+			Name:  "null-style",
+			Value: "pointer",
+			Usage: "The type of fields for nullable columns: pointer (*string), sql (sql.NullString) or none (string).",
+		},
+		cli.BoolFlag{
+			Name:  "no-comments",
+			Usage: "Leave out the comments on tables and columns, which are otherwise written above the structs and fields.",
+		},
+		cli.BoolFlag{
+			Name:  "defaults",
+			Usage: "Note the DEFAULT of each column that has one in a comment above its field.",
+		},
+		cli.BoolFlag{
+			Name:  "type-comments",
+			Usage: "Follow each field with a comment giving the SQL type of its column.",
+		},
+		cli.BoolFlag{
+			Name:  "maps",
+			Usage: "Generate ToMap and FromMap methods per struct, which convert it to and from a map keyed by column name.",
+		},
+		cli.BoolFlag{
+			Name:  "stringer",
+			Usage: "Generate a String method per struct, which shows the primary key, like User{id=42}.",
+		},
+		cli.BoolFlag{
+			Name:  "copy",
+			Usage: "Generate a CopyFrom function per table that bulk loads with the Postgres COPY protocol.",
+		},
+		cli.StringFlag{
+			Name:  "relations",
+			Value: "",
+			Usage: "A file of 'parent.pk -> child.fk' lines, one per relation. Emits the relations used by DeleteCascade.",
+		},
+		cli.StringFlag{
+			Name:  "ddl",
+			Value: "",
+			Usage: "Read the tables from CREATE TABLE statements in this SQL file instead of a database. Postgres syntax only.",
+		},
+		cli.StringFlag{
+			Name:  "from-structs",
+			Value: "",
+			Usage: "Write Postgres CREATE TABLE statements for the structable structs of the Go package in this directory, instead of generating code.",
+		},
+		cli.IntFlag{
+			Name:  "max-identifier-length",
+			Value: 63,
+			Usage: "The longest identifier the database keeps before truncating. Used to warn about unreliable SERIAL detection.",
+		},
+		cli.BoolFlag{
+			Name:  "postgis",
+			Usage: "Map PostGIS geometry and geography columns to a generated Geometry type.",
+		},
+		cli.BoolFlag{
+			Name:  "trim-char",
+			Usage: "Map Postgres char(n) columns to a generated TrimmedString type that drops the space padding.",
+		},
 	}
 
 	app.Run(os.Args)
 }
 
-func driver(c *cli.Context) string {
-	return c.String("driver")
-}
-func conn(c *cli.Context) string {
-	return os.ExpandEnv(c.String("connection"))
+// genConfig holds the settings that change how tables are turned into structs.
+type genConfig struct {
+	driver    string
+	maxIdent  int
+	postgis   bool
+	trimChar  bool
+	copy      bool
+	maps      bool
+	stringer  bool
+	gorm      bool
+	comments  bool
+	noComment bool
+	defaults  bool
+	json      bool
+	jsonCase  string
+	omitEmpty bool
+	nullStyle string
+	fkFields  bool
+	strict    bool
+	singular  bool
+	prefixes  []string
+	schema    string
+	schemaPre bool
+	jsonBytes bool
+	skipViews bool
+	requirePK bool
+	// softDelete tags nullable deleted_at columns with SOFT_DELETE.
+	softDelete bool
+	// decimals is empty, or shopspring for shopspring/decimal.
+	decimals string
+	// uuids is string, or google for github.com/google/uuid.
+	uuids     string
+	enums     bool
+	relations map[string][]relationDesc
+	// enumTypes maps the enum types of the database to their Go types.
+	enumTypes map[string]*enumDesc
+	// fetched holds the columns and keys of the tables of the schema.
+	fetched *fetched
 }
 
-// dest gets the destination output writer.
-func dest(c *cli.Context) io.Writer {
-	if out := c.String("file"); out != "" {
-		f, err := os.Create(out)
-		if err != nil {
-			panic(f)
-		}
-		return f
+func newGenConfig(c *cli.Context) *genConfig {
+	return &genConfig{
+		driver:     driver(c),
+		maxIdent:   c.Int("max-identifier-length"),
+		postgis:    c.Bool("postgis"),
+		trimChar:   c.Bool("trim-char"),
+		copy:       c.Bool("copy"),
+		maps:       c.Bool("maps"),
+		stringer:   c.Bool("stringer"),
+		gorm:       c.Bool("gorm"),
+		comments:   c.Bool("type-comments"),
+		noComment:  c.Bool("no-comments"),
+		defaults:   c.Bool("defaults"),
+		json:       c.Bool("json"),
+		jsonCase:   c.String("json-case"),
+		omitEmpty:  c.Bool("json-omitempty"),
+		nullStyle:  c.String("null-style"),
+		fkFields:   c.Bool("fk-fields"),
+		strict:     c.Bool("strict"),
+		singular:   c.Bool("singular"),
+		prefixes:   commaList(c.String("strip-prefix")),
+		schema:     c.String("schema"),
+		schemaPre:  c.Bool("schema-prefix"),
+		jsonBytes:  c.Bool("json-bytes"),
+		skipViews:  c.Bool("skip-views"),
+		requirePK:  c.Bool("require-pk"),
+		softDelete: c.Bool("soft-delete"),
+		decimals:   c.String("decimal-type"),
+		uuids:      c.String("uuid-type"),
+		enums:      c.Bool("enums"),
 	}
-	return os.Stdout
 }
 
-func tableList(c *cli.Context) []string {
-	z := c.String("tables")
-	if z != "" {
-		return strings.Split(z, ",")
+// apply sets the parts of a struct description that come from the settings
+// rather than from the table.
+func (cfg *genConfig) apply(f *structDesc, dbType string) {
+	f.DBType = dbType
+	f.Relations = cfg.relations[f.TableName]
+	for i := range f.Fields {
+		if cfg.jsonBytes && f.Fields[i].Type == "json.RawMessage" {
+			f.Fields[i].Type = "[]byte"
+		}
+		if !f.Fields[i].NotNull {
+			f.Fields[i].Type = nullType(f.Fields[i].Type, cfg.nullStyle)
+		}
+		// structable sets a soft delete field to a *time.Time.
+		if cfg.softDelete && f.Fields[i].Column == "deleted_at" && f.Fields[i].Type == "*time.Time" {
+			f.Fields[i].Tag += ",SOFT_DELETE"
+		}
+	}
+	if len(f.Key) > 1 {
+		// Number the columns of a composite key, so that structable keeps
+		// them in key order.
+		for i := range f.Fields {
+			for n, k := range f.Key {
+				if f.Fields[i].Column == k {
+					f.Fields[i].Tag += fmt.Sprintf(",KEY_ORDER=%d", n+1)
+				}
+			}
+		}
+	}
+	f.Copy = cfg.copy
+	f.Maps = cfg.maps
+	f.Stringer = cfg.stringer
+	f.TypeComments = cfg.comments
+	if cfg.json {
+		for i := range f.Fields {
+			f.Fields[i].JSON = jsonKey(f.Fields[i].Column, cfg.jsonCase)
+			if cfg.omitEmpty && !f.Fields[i].NotNull {
+				f.Fields[i].JSON += ",omitempty"
+			}
+		}
 	}
-	return []string{}
 }
 
-func cxdie(c *cli.Context, err error) {
-	fmt.Fprintf(os.Stderr, "Failed to connect to %s (type %s): %s", conn(c), driver(c), err)
-	os.Exit(1)
+// defaultComment notes the DEFAULT of a column in the comment of its field,
+// with --defaults.
+func (cfg *genConfig) defaultComment(f *fieldDesc, c *column) {
+	if cfg.defaults && c.Default.Valid {
+		f.Comment = strings.TrimSpace(f.Comment + "\ndefault: " + c.Default.String)
+	}
 }
 
-var funcMap = map[string]interface{}{
-	"ann": func(tag, val string) string {
-		return fmt.Sprintf("`%s:\"%s\"`", tag, val)
-	},
+// decimal makes a numeric or decimal field a decimal.Decimal, with the
+// shopspring --decimal-type, and notes the precision and scale.
+func (cfg *genConfig) decimal(f *fieldDesc, c *column) {
+	if cfg.decimals != "shopspring" || (c.DataType != "numeric" && c.DataType != "decimal") {
+		return
+	}
+	f.Type = "decimal.Decimal"
+	if c.Precision.Valid {
+		f.Comment = fmt.Sprintf("%s(%d,%d)", c.DataType, c.Precision.Int64, c.Scale.Int64)
+	}
 }
 
-func importTables(c *cli.Context) {
-	ttt := template.Must(template.New("st").Funcs(funcMap).Parse(structTemplate))
-	cxn, err := sql.Open(driver(c), conn(c))
-	if err != nil {
-		cxdie(c, err)
+// structName returns the name of the struct for a table, which starts with
+// the schema with --schema-prefix.
+func (cfg *genConfig) structName(tbl string) string {
+	if cfg.schemaPre && cfg.driver == "postgres" {
+		tbl = cfg.schema + "." + tbl
 	}
-	// Many drivers defer connections until the first statement. We test
-	// that here.
-	if err := cxn.Ping(); err != nil {
-		cxdie(c, err)
+	tbl = cfg.stripPrefix(tbl)
+	if cfg.singular {
+		return singularName(tbl)
 	}
-	defer cxn.Close()
+	return goName(tbl)
+}
 
-	// Set up Squirrel
-	stmts := squirrel.NewStmtCacher(cxn)
-	bldr := squirrel.StatementBuilder.RunWith(stmts)
-	if driver(c) == "postgres" {
-		bldr = bldr.PlaceholderFormat(squirrel.Dollar)
+// stripPrefix removes the first --strip-prefix prefix that a table name
+// starts with. The schema of a qualified name is kept, and a prefix that is
+// the whole name is not removed.
+func (cfg *genConfig) stripPrefix(tbl string) string {
+	i := strings.LastIndex(tbl, ".") + 1
+	for _, p := range cfg.prefixes {
+		if strings.HasPrefix(tbl[i:], p) && len(tbl[i:]) > len(p) {
+			return tbl[:i] + tbl[i+len(p):]
+		}
 	}
+	return tbl
+}
 
-	// Set up destination
-	out := dest(c)
-	fmt.Fprintf(out, fileHeader, c.String("package"))
-
-	tables := tableList(c)
+// qualify returns the name structable uses for a table. Tables outside the
+// default public schema are qualified with their schema.
+func (cfg *genConfig) qualify(tbl string) string {
+	if cfg.driver != "postgres" || cfg.schema == "public" || cfg.schema == "" {
+		return tbl
+	}
+	return cfg.schema + "." + tbl
+}
 
-	if len(tables) == 0 {
-		tables, err = publicTables(bldr)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Cannot fetch list of tables: %s\n", err)
-			os.Exit(2)
+// usedEnums returns the enum types of the fields, ordered by name.
+func (cfg *genConfig) usedEnums(descs []*structDesc) []*enumDesc {
+	used := map[string]bool{}
+	for _, d := range descs {
+		for _, f := range d.Fields {
+			used[strings.TrimPrefix(f.Type, "*")] = true
 		}
 	}
-
-	for _, t := range tables {
-		f, err := importTable(t, bldr, driver(c))
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to import table %s: %s", t, err)
+	enums := []*enumDesc{}
+	for _, e := range cfg.enumTypes {
+		if used[e.Name] {
+			enums = append(enums, e)
 		}
-
-		//fmt.Fprintf(out, "%s %s %s\n", f.StructName, f.TableName, f.Fields)
-		ttt.Execute(out, f)
 	}
+	sort.Slice(enums, func(i, j int) bool { return enums[i].Name < enums[j].Name })
+	return enums
 }
 
-type column struct {
-	Name, DataType string
-	Max            int64
+// sqlNullTypes maps Go types to the database/sql types that add NULL to them.
+var sqlNullTypes = map[string]string{
+	"string":    "sql.NullString",
+	"int":       "sql.NullInt64",
+	"int8":      "sql.NullInt64",
+	"int16":     "sql.NullInt64",
+	"int32":     "sql.NullInt64",
+	"int64":     "sql.NullInt64",
+	"float32":   "sql.NullFloat64",
+	"float64":   "sql.NullFloat64",
+	"bool":      "sql.NullBool",
+	"time.Time": "sql.NullTime",
+	// shopspring/decimal has its own.
+	"decimal.Decimal": "decimal.NullDecimal",
+	"uuid.UUID":       "uuid.NullUUID",
 }
 
-func publicTables(b squirrel.StatementBuilderType) ([]string, error) {
-	rows, err := b.Select("table_name").From("INFORMATION_SCHEMA.TABLES").
-		Where("table_schema = 'public'").Query()
-
-	res := []string{}
-	if err != nil {
-		return res, err
+// nullType returns the type of a field for a nullable column.
+//
+// With the pointer style, the type becomes a pointer, which is nil for NULL.
+// With the sql style, it becomes one of the sql.Null types, if there is one.
+// Slices can be nil already, and are kept, as are the pq array types and
+// every type with the none style.
+func nullType(goType, style string) string {
+	if style == "none" || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "pq.") {
+		return goType
+	}
+	if style == "sql" {
+		if nt, ok := sqlNullTypes[goType]; ok {
+			return nt
+		}
 	}
+	return "*" + goType
+}
 
-	for rows.Next() {
-		var s string
-		rows.Scan(&s)
-		res = append(res, s)
+// jsonKey returns the JSON key for a column. With the camel case, user_id
+// becomes userId. Otherwise the column name is used as it is.
+func jsonKey(column, jsonCase string) string {
+	if jsonCase != "camel" {
+		return column
 	}
+	words := strings.Split(column, "_")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.Title(words[i])
+	}
+	return strings.Join(words, "")
+}
 
-	return res, nil
+// driverAliases maps other names of the drivers to the names used here.
+var driverAliases = map[string]string{
+	"postgresql": "postgres",
+	"sqlite":     "sqlite3",
+	"godror":     "oracle",
 }
 
-// importTable reads a table definition and writes a corresponding struct.
-// SELECT table_name, column_name, data_type, character_maximum_length
-//   FROM INFORMATION_SCHEMA.COLUMNS WHERE table_name = 'goose_db_version'
-func importTable(tbl string, b squirrel.StatementBuilderType, driver string) (*structDesc, error) {
+// driver returns the name of the --driver, in lower case and with aliases
+// resolved, so that PostgreSQL becomes postgres.
+func driver(c *cli.Context) string {
+	name := strings.ToLower(strings.TrimSpace(c.String("driver")))
+	if alias, ok := driverAliases[name]; ok {
+		return alias
+	}
+	return name
+}
 
-	pks, err := primaryKeyField(tbl, b)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting primary keys: %s", err)
+// sqlDriver returns the name a driver is registered under with database/sql.
+func sqlDriver(name string) string {
+	if name == "oracle" {
+		return "godror"
 	}
+	return name
+}
+func conn(c *cli.Context) string {
+	return os.ExpandEnv(c.String("connection"))
+}
 
-	q := b.Select("column_name, data_type, character_maximum_length").
-		From("INFORMATION_SCHEMA.COLUMNS").
-		Where("table_name = ?", tbl)
+// generatedMark is the line that marks a file as written by schema2struct.
+const generatedMark = "// This file is automatically generated by schema2struct."
 
-	rows, err := q.Query()
-	if err != nil {
-		return nil, err
+// writeOutput writes the generated code to the output file, creating its
+// directory if needed, or to stdout if there is no output file.
+//
+// An existing file is only replaced if schema2struct generated it, or with
+// --force, so that a mistyped path cannot clobber hand written code. Only
+// the text of the mark is looked for, as SQL files comment it with --.
+func writeOutput(c *cli.Context, code []byte) error {
+	out := c.String("file")
+	if out == "" {
+		_, err := os.Stdout.Write(code)
+		return err
 	}
-	defer rows.Close()
 
-	ff := []string{}
-	for rows.Next() {
-		c := &column{}
-		var length sql.NullInt64
-		if err := rows.Scan(&c.Name, &c.DataType, &length); err != nil {
-			return nil, err
+	if old, err := ioutil.ReadFile(out); err == nil && !c.Bool("force") && !bytes.Contains(old, []byte(strings.TrimPrefix(generatedMark, "// "))) {
+		return fmt.Errorf("%s exists and was not generated by schema2struct, use --force to replace it", out)
+	}
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
+
+// finish writes the generated code, or exits if it cannot.
+func finish(c *cli.Context, code []byte) {
+	if err := writeOutput(c, code); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write output: %s\n", err)
+		os.Exit(2)
+	}
+}
+
+func tableList(c *cli.Context) []string {
+	z := c.String("tables")
+	if z != "" {
+		return strings.Split(z, ",")
+	}
+	return []string{}
+}
+
+// commaList splits a comma separated flag value, skipping empty items.
+func commaList(z string) []string {
+	res := []string{}
+	for _, item := range strings.Split(z, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
 		}
-		c.Max = length.Int64
-		switch driver {
+	}
+	return res
+}
+
+// checkDriver exits with instructions if the driver was not built in.
+//
+// sql.Open would only fail with "unknown driver", which does not say what
+// to do about it.
+func checkDriver(name string) {
+	for _, d := range sql.Drivers() {
+		if d == sqlDriver(name) {
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "The %s driver is not built into schema2struct. Built in drivers: %s\n", name, strings.Join(sql.Drivers(), ", "))
+	switch name {
+	case "oracle":
+		fmt.Fprintln(os.Stderr, "Rebuild with the oracle build tag, using 'make build-oracle'.")
+	case "sqlite3":
+		fmt.Fprintln(os.Stderr, "Rebuild with the sqlite build tag, using 'make build-sqlite'.")
+	default:
+		fmt.Fprintf(os.Stderr, "Rebuild with a blank import of the package that registers %s.\n", name)
+	}
+	os.Exit(1)
+}
+
+func cxdie(c *cli.Context, err error) {
+	fmt.Fprintf(os.Stderr, "Failed to connect to %s (type %s): %s", conn(c), driver(c), err)
+	os.Exit(1)
+}
+
+var funcMap = map[string]interface{}{
+	"ann": func(tag, val string) string {
+		return fmt.Sprintf("`%s:\"%s\"`", tag, val)
+	},
+	// comment turns text into line comments. gofmt indents them.
+	"comment": func(text string) string {
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("// "+strings.TrimSpace(l), " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+// dbType returns the type generated code uses for database handles.
+func dbType(c *cli.Context) string {
+	if c.String("db-interface") != "" && !c.Bool("gorm") {
+		return c.String("db-interface")
+	}
+	return "squirrel.DBProxyBeginner"
+}
+
+// writeHeader writes the file header, importing what the fields of the
+// structs need.
+func writeHeader(c *cli.Context, out io.Writer, descs []*structDesc, enums []*enumDesc) {
+	htt := template.Must(template.New("hd").Parse(fileHeader))
+	hd := &headerDesc{
+		Package:     c.String("package"),
+		DBInterface: c.String("db-interface"),
+		PostGIS:     c.Bool("postgis"),
+		TrimChar:    c.Bool("trim-char"),
+		Copy:        c.Bool("copy"),
+		Maps:        c.Bool("maps"),
+		Stringer:    c.Bool("stringer"),
+		GORM:        c.Bool("gorm"),
+		Time:        usesPackage(descs, "time."),
+		SQL:         usesPackage(descs, "sql."),
+		PQ:          usesPackage(descs, "pq."),
+		JSON:        usesPackage(descs, "json."),
+		Decimal:     usesPackage(descs, "decimal."),
+		UUID:        usesPackage(descs, "uuid."),
+		Enums:       enums,
+		Driver:      driver(c),
+	}
+	if hd.GORM {
+		// GORM models never take a database handle.
+		hd.DBInterface, hd.Copy = "", false
+	}
+	htt.Execute(out, hd)
+}
+
+func importTables(c *cli.Context) {
+	cfg := newGenConfig(c)
+	if _, ok := driverPackages[cfg.driver]; !ok {
+		fmt.Fprintf(os.Stderr, "Unknown --driver %s, use postgres, mysql, sqlite3 or oracle\n", c.String("driver"))
+		os.Exit(2)
+	}
+	if cfg.jsonCase != "column" && cfg.jsonCase != "camel" {
+		fmt.Fprintf(os.Stderr, "Unknown --json-case %s, use column or camel\n", cfg.jsonCase)
+		os.Exit(2)
+	}
+	if cfg.nullStyle != "pointer" && cfg.nullStyle != "sql" && cfg.nullStyle != "none" {
+		fmt.Fprintf(os.Stderr, "Unknown --null-style %s, use pointer, sql or none\n", cfg.nullStyle)
+		os.Exit(2)
+	}
+	if cfg.decimals != "" && cfg.decimals != "shopspring" {
+		fmt.Fprintf(os.Stderr, "Unknown --decimal-type %s, use shopspring\n", cfg.decimals)
+		os.Exit(2)
+	}
+	if cfg.uuids != "string" && cfg.uuids != "google" {
+		fmt.Fprintf(os.Stderr, "Unknown --uuid-type %s, use string or google\n", cfg.uuids)
+		os.Exit(2)
+	}
+	tpl := structTemplate
+	if cfg.gorm {
+		tpl = gormTemplate
+	}
+	ttt := template.Must(template.New("st").Funcs(funcMap).Parse(tpl))
+	if file := c.String("type-map"); file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err == nil {
+			err = json.Unmarshal(data, &userTypes)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read type map from %s: %s\n", file, err)
+			os.Exit(2)
+		}
+	}
+	if file := c.String("relations"); file != "" {
+		rels, err := readRelations(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read relations from %s: %s\n", file, err)
+			os.Exit(2)
+		}
+		cfg.relations = rels
+	}
+
+	// The reverse direction, from structs to DDL.
+	if dir := c.String("from-structs"); dir != "" {
+		ddl, err := exportDDL(dir, tableList(c))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read structs from %s: %s\n", dir, err)
+			os.Exit(2)
+		}
+		finish(c, ddl)
+		return
+	}
+
+	// With a DDL file, no database is needed at all.
+	if ddl := c.String("ddl"); ddl != "" {
+		descs, err := importDDL(ddl, tableList(c), cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot read tables from %s: %s\n", ddl, err)
+			os.Exit(2)
+		}
+		render(c, ttt, cfg, descs)
+		return
+	}
+
+	render(c, ttt, cfg, importDB(c, cfg))
+}
+
+// importDB reads the tables from the database, exiting if any of them cannot
+// be read.
+func importDB(c *cli.Context, cfg *genConfig) []*structDesc {
+	if cfg.copy && !cfg.gorm && cfg.driver != "postgres" {
+		fmt.Fprintf(os.Stderr, "--copy uses the Postgres COPY protocol, and cannot be used with %s\n", cfg.driver)
+		os.Exit(2)
+	}
+
+	checkDriver(driver(c))
+	cxn, err := sql.Open(sqlDriver(driver(c)), conn(c))
+	if err != nil {
+		cxdie(c, err)
+	}
+	// Many drivers defer connections until the first statement. We test
+	// that here.
+	if err := cxn.Ping(); err != nil {
+		cxdie(c, err)
+	}
+	defer cxn.Close()
+
+	// Set up Squirrel
+	stmts := squirrel.NewStmtCacher(cxn)
+	bldr := squirrel.StatementBuilder.RunWith(stmts)
+	switch driver(c) {
+	case "postgres":
+		bldr = bldr.PlaceholderFormat(squirrel.Dollar)
+	case "oracle":
+		bldr = bldr.PlaceholderFormat(squirrel.Colon)
+	}
+
+	if cfg.enums && cfg.driver == "postgres" {
+		if cfg.enumTypes, err = pgEnums(bldr); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot fetch enum types: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	schemas, tables := schemaTables(c, cfg)
+
+	descs := []*structDesc{}
+	failed := false
+	for _, schema := range schemas {
+		// Each schema is read with its own copy of the settings.
+		scfg := *cfg
+		scfg.schema = schema
+		if len(tables[schema]) == 0 {
+			switch cfg.driver {
+			case "oracle":
+				tables[schema], err = oracleTables(bldr)
+			case "sqlite3":
+				tables[schema], err = sqliteTables(bldr)
+			default:
+				tables[schema], err = publicTables(bldr, cfg.driver, schema)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot fetch list of tables: %s\n", err)
+				os.Exit(2)
+			}
+		}
+
+		if cfg.driver == "postgres" || cfg.driver == "mysql" {
+			if scfg.fetched, err = fetchTables(tables[schema], bldr, &scfg); err != nil {
+				fmt.Fprintf(os.Stderr, "Cannot fetch the columns of the tables: %s\n", err)
+				os.Exit(2)
+			}
+		}
+		for _, t := range tables[schema] {
+			f, err := importTable(t, bldr, &scfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to import table %s: %s\n", scfg.qualify(t), err)
+				failed = true
+				continue
+			}
+			if f.View && cfg.skipViews {
+				continue
+			}
+			descs = append(descs, f)
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+	return descs
+}
+
+// schemaTables returns the schemas to read, and the tables given with
+// --tables for each of them.
+//
+// Only Postgres reads more than one schema. Its tables may be given with
+// their schema, like audit.events; the others are read from the first
+// schema. Without --tables, every schema is read in full.
+func schemaTables(c *cli.Context, cfg *genConfig) ([]string, map[string][]string) {
+	schemas := []string{cfg.schema}
+	if cfg.driver == "postgres" {
+		if schemas = commaList(cfg.schema); len(schemas) == 0 {
+			schemas = []string{"public"}
+		}
+	}
+
+	// With --tables, only the schemas of the given tables are read.
+	tables := map[string][]string{}
+	listed := []string{}
+	for _, t := range tableList(c) {
+		schema := schemas[0]
+		if i := strings.Index(t, "."); i > 0 && cfg.driver == "postgres" {
+			schema, t = t[:i], t[i+1:]
+		}
+		if len(tables[schema]) == 0 {
+			listed = append(listed, schema)
+		}
+		tables[schema] = append(tables[schema], t)
+	}
+	if len(listed) > 0 {
+		schemas = listed
+	}
+	return schemas, tables
+}
+
+// render generates the code for the tables and writes it out.
+//
+// The code is generated into a buffer, so that nothing is written unless
+// every table was generated.
+func render(c *cli.Context, ttt *template.Template, cfg *genConfig, descs []*structDesc) {
+	if cfg.requirePK {
+		keyed := []*structDesc{}
+		for _, d := range descs {
+			if d.NoKey() {
+				fmt.Fprintf(os.Stderr, "Skipping table %s, which has no primary key.\n", d.TableName)
+				continue
+			}
+			keyed = append(keyed, d)
+		}
+		descs = keyed
+	}
+	if c.Bool("list") {
+		listTables(os.Stdout, descs)
+		return
+	}
+	if cfg.strict {
+		unmapped := false
+		for _, d := range descs {
+			for _, f := range d.Fields {
+				if f.Unmapped {
+					fmt.Fprintf(os.Stderr, "Column %s.%s has type %s, which has no Go type. Map it with --type-map.\n", d.TableName, f.Column, f.SQLType)
+					unmapped = true
+				}
+			}
+		}
+		if unmapped {
+			os.Exit(2)
+		}
+	}
+
+	for _, f := range descs {
+		cfg.apply(f, dbType(c))
+		uniqueFieldNames(f)
+	}
+	// Tables of different schemas may have the same name.
+	names := map[string]string{}
+	for _, d := range descs {
+		if other, ok := names[d.StructName]; ok {
+			fmt.Fprintf(os.Stderr, "Tables %s and %s both map to the struct %s. Use --schema-prefix to tell them apart.\n", other, d.TableName, d.StructName)
+			os.Exit(2)
+		}
+		names[d.StructName] = d.TableName
+	}
+	if cfg.fkFields && !cfg.gorm {
+		setFKFields(descs)
+	}
+
+	out := &bytes.Buffer{}
+	writeHeader(c, out, descs, cfg.usedEnums(descs))
+	for _, f := range descs {
+		//fmt.Fprintf(out, "%s %s %s\n", f.StructName, f.TableName, f.Fields)
+		if err := ttt.Execute(out, f); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate table %s: %s\n", f.TableName, err)
+			os.Exit(2)
+		}
+	}
+
+	// Formatting also catches template bugs that produce invalid Go.
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\nFailed to format the generated code: %s\n", out.Bytes(), err)
+		os.Exit(2)
+	}
+	finish(c, code)
+}
+
+// listTables writes the tables that would be generated, one per line, with
+// their primary key columns.
+func listTables(out io.Writer, descs []*structDesc) {
+	for _, d := range descs {
+		key := strings.Join(d.Key, ",")
+		if key == "" {
+			key = "(no primary key)"
+		}
+		fmt.Fprintf(out, "%s\t%s\n", d.TableName, key)
+	}
+}
+
+// usesPackage reports whether the type of any field is from a package, given
+// by its prefix, like "time.".
+func usesPackage(descs []*structDesc, prefix string) bool {
+	for _, d := range descs {
+		for _, f := range d.Fields {
+			if strings.HasPrefix(strings.TrimPrefix(f.Type, "*"), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readRelations reads a relations file, returning the relations of each
+// parent table.
+//
+// Each line of the file has the form `parent.pk -> child.fk`, saying that the
+// fk column of the child table holds the primary key of the parent table.
+// Blank lines and lines starting with # are ignored.
+func readRelations(file string) (map[string][]relationDesc, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	rels := map[string][]relationDesc{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sides := strings.Split(line, "->")
+		if len(sides) != 2 {
+			return nil, fmt.Errorf("line %d: expected 'parent.pk -> child.fk'", i+1)
+		}
+		parent := strings.Split(strings.TrimSpace(sides[0]), ".")
+		child := strings.Split(strings.TrimSpace(sides[1]), ".")
+		if len(parent) != 2 || len(child) != 2 {
+			return nil, fmt.Errorf("line %d: expected 'parent.pk -> child.fk'", i+1)
+		}
+		rels[parent[0]] = append(rels[parent[0]], relationDesc{Table: child[0], Column: child[1]})
+	}
+	return rels, nil
+}
+
+type column struct {
+	Name, DataType, UDTName string
+	// ColumnType is the full MySQL type, like tinyint(1), or the type a
+	// SQLite column was declared with.
+	ColumnType string
+	Max        int64
+	NotNull    bool
+	// Precision and Scale are the declared digits of a numeric column.
+	Precision, Scale sql.NullInt64
+	// Comment is the comment on the column, if any.
+	Comment sql.NullString
+	// Generated is set for columns computed by the database, which cannot
+	// be written.
+	Generated bool
+	// Default is the DEFAULT expression of the column, if any.
+	Default sql.NullString
+	// AutoIncrement is set for MySQL AUTO_INCREMENT columns.
+	AutoIncrement bool
+}
+
+// publicTables lists the tables of the given schema (Postgres) or of the
+// current database (MySQL).
+func publicTables(b squirrel.StatementBuilderType, driver, schema string) ([]string, error) {
+	q := b.Select("table_name").From("INFORMATION_SCHEMA.TABLES").OrderBy("table_name")
+	if driver == "mysql" {
+		q = q.Where("table_schema = DATABASE()")
+	} else {
+		q = q.Where("table_schema = ?", schema)
+	}
+	rows, err := q.Query()
+
+	res := []string{}
+	if err != nil {
+		return res, err
+	}
+
+	for rows.Next() {
+		var s string
+		rows.Scan(&s)
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// importTable reads a table definition and writes a corresponding struct.
+//
+// On Postgres and MySQL, everything about the table is taken from
+// cfg.fetched, which fetchTables fills for all the tables at once.
+func importTable(tbl string, b squirrel.StatementBuilderType, cfg *genConfig) (*structDesc, error) {
+	switch cfg.driver {
+	case "oracle":
+		return importOracleTable(tbl, b, cfg)
+	case "sqlite3":
+		return importSQLiteTable(tbl, b, cfg)
+	}
+
+	view := cfg.fetched.views[tbl]
+	pks := []string{}
+	if !view {
+		// Views have no primary key.
+		pks = cfg.fetched.keys[tbl]
+	}
+
+	ff := []fieldDesc{}
+	for _, c := range cfg.fetched.columns[tbl] {
+		var f fieldDesc
+		switch cfg.driver {
 		case "mysql":
-			ff = append(ff, structFieldMySQL(c, pks, tbl, b))
+			f = structFieldMySQL(c, pks, tbl)
+			cfg.decimal(&f, c)
 		case "postgres":
-			ff = append(ff, structField(c, pks, tbl, b))
+			f = structField(c, pks, tbl, cfg)
+		}
+		if !cfg.noComment && c.Comment.String != "" {
+			f.Comment = strings.TrimSpace(c.Comment.String + "\n" + f.Comment)
 		}
+		cfg.defaultComment(&f, c)
+		ff = append(ff, f)
 	}
+	fks := cfg.fetched.fks[tbl]
+	for i := range ff {
+		if fk := fks[ff[i].Column]; fk != nil {
+			// Foreign keys are read within the schema.
+			fk.Table = cfg.qualify(fk.Table)
+			ff[i].FK = fk
+		}
+	}
+
+	// Views have no constraints, so they have no unique keys either.
+	uniques := cfg.fetched.uniques[tbl]
+	if uniques == nil {
+		uniques = [][]string{}
+	}
+	for _, u := range uniques {
+		if len(u) != 1 {
+			continue
+		}
+		for i := range ff {
+			if ff[i].Column == u[0] {
+				ff[i].Unique = true
+				ff[i].Tag += ",UNIQUE"
+			}
+		}
+	}
+
 	sd := &structDesc{
-		StructName: goName(tbl),
-		TableName:  tbl,
+		StructName: cfg.structName(tbl),
+		TableName:  cfg.qualify(tbl),
 		Fields:     ff,
+		Key:        pks,
+		View:       view,
+		Unique:     uniques,
+	}
+	if !cfg.noComment && !view {
+		sd.Comment = cfg.fetched.comments[tbl]
 	}
 
 	return sd, nil
 }
 
-func primaryKeyField(tbl string, b squirrel.StatementBuilderType) ([]string, error) {
-	q := b.Select("column_name").
+// fetched holds the columns, keys and constraints of the tables of a schema,
+// by table, and the sequences of the schema.
+type fetched struct {
+	columns map[string][]*column
+	keys    map[string][]string
+	views   map[string]bool
+	// comments holds the comments on the tables that have one.
+	comments map[string]string
+	fks      map[string]map[string]*fkDesc
+	uniques  map[string][][]string
+	// sequences holds the names of the sequences, on Postgres.
+	sequences map[string]bool
+}
+
+// fetchTables reads the columns, keys and constraints of the given tables,
+// and the sequences of the schema, with one query each, rather than with a
+// few queries per table.
+func fetchTables(tables []string, b squirrel.StatementBuilderType, cfg *genConfig) (*fetched, error) {
+	f := &fetched{}
+	var err error
+	if f.columns, err = fetchColumns(tables, b, cfg); err != nil {
+		return nil, err
+	}
+	if f.views, f.comments, err = tableInfo(tables, b, cfg); err != nil {
+		return nil, err
+	}
+	if f.fks, err = foreignKeys(tables, b, cfg.driver, cfg.schema); err != nil {
+		return nil, err
+	}
+	if f.uniques, err = uniqueKeys(tables, b, cfg.driver, cfg.schema); err != nil {
+		return nil, err
+	}
+	// Without the keys or the sequences, the tables are still generated,
+	// but without PRIMARY_KEY or SERIAL columns.
+	if f.keys, err = primaryKeys(tables, b, cfg.driver, cfg.schema); err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting primary keys: %s\n", err)
+	}
+	if cfg.driver == "postgres" {
+		if f.sequences, err = sequences(b, cfg.schema); err != nil {
+			fmt.Fprintf(os.Stderr, "Error looking up the sequences of %s: %s\n", cfg.schema, err)
+		}
+	}
+	return f, nil
+}
+
+// fetchColumns reads the columns of tables, in table order.
+func fetchColumns(tables []string, b squirrel.StatementBuilderType, cfg *genConfig) (map[string][]*column, error) {
+	cols := "table_name, column_name, data_type, character_maximum_length, is_nullable, numeric_precision, numeric_scale, column_default"
+	switch cfg.driver {
+	case "postgres":
+		// Only Postgres has the udt_name column. Comments are kept in the
+		// catalog, by table and column number.
+		cols += ", udt_name, col_description((quote_ident(table_schema) || '.' || quote_ident(table_name))::regclass, ordinal_position), is_generated"
+	case "mysql":
+		// Only MySQL has column_type, which tells tinyint(1) apart.
+		cols += ", column_type, column_comment, extra"
+	}
+	// Without an order, the database may return the columns in any order,
+	// and the fields would move around between runs.
+	q := b.Select(cols).
+		From("INFORMATION_SCHEMA.COLUMNS").
+		Where(squirrel.Eq{"table_name": tables}).
+		OrderBy("table_name", "ordinal_position")
+	if cfg.driver == "mysql" {
+		// MySQL lists the tables of every database.
+		q = q.Where("table_schema = DATABASE()")
+	} else {
+		q = q.Where("table_schema = ?", cfg.schema)
+	}
+
+	rows, err := q.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string][]*column{}
+	for rows.Next() {
+		c := &column{}
+		var tbl string
+		var length sql.NullInt64
+		var nullable, generated string
+		dest := []interface{}{&tbl, &c.Name, &c.DataType, &length, &nullable, &c.Precision, &c.Scale, &c.Default}
+		switch cfg.driver {
+		case "postgres":
+			dest = append(dest, &c.UDTName, &c.Comment, &generated)
+		case "mysql":
+			dest = append(dest, &c.ColumnType, &c.Comment, &generated)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		c.Max = length.Int64
+		c.NotNull = nullable == "NO"
+		// MySQL lists VIRTUAL GENERATED or STORED GENERATED in extra, but
+		// also DEFAULT_GENERATED for columns with a DEFAULT expression.
+		c.Generated = generated == "ALWAYS" || strings.HasSuffix(generated, " GENERATED")
+		c.AutoIncrement = generated == "auto_increment"
+		res[tbl] = append(res[tbl], c)
+	}
+	return res, rows.Err()
+}
+
+// tableInfo reports which of tables are views, and returns the comments on
+// the tables.
+func tableInfo(tables []string, b squirrel.StatementBuilderType, cfg *genConfig) (map[string]bool, map[string]string, error) {
+	q := b.Select("table_name, table_type").From("INFORMATION_SCHEMA.TABLES").
+		Where(squirrel.Eq{"table_name": tables})
+	if cfg.driver == "mysql" {
+		q = q.Column("table_comment").Where("table_schema = DATABASE()")
+	} else {
+		// Postgres keeps the comments in the catalog.
+		q = q.Column("obj_description((quote_ident(table_schema) || '.' || quote_ident(table_name))::regclass, 'pg_class')").
+			Where("table_schema = ?", cfg.schema)
+	}
+
+	rows, err := q.Query()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	views, comments := map[string]bool{}, map[string]string{}
+	for rows.Next() {
+		var tbl, tt string
+		var comment sql.NullString
+		if err := rows.Scan(&tbl, &tt, &comment); err != nil {
+			return nil, nil, err
+		}
+		views[tbl] = tt == "VIEW"
+		if comment.String != "" {
+			comments[tbl] = comment.String
+		}
+	}
+	return views, comments, rows.Err()
+}
+
+// primaryKeys returns the primary key columns of tables, in key order.
+func primaryKeys(tables []string, b squirrel.StatementBuilderType, driver, schema string) (map[string][]string, error) {
+	q := b.Select("c.table_name, column_name").
 		From("INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS c").
-		LeftJoin("INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS t USING(constraint_name)").
-		Where("t.table_name = ? AND t.constraint_type = 'PRIMARY KEY'", tbl).
+		LeftJoin("INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS t USING(constraint_schema, constraint_name)").
+		Where("t.table_schema = ? AND t.constraint_type = 'PRIMARY KEY'", schema).
+		Where(squirrel.Eq{"t.table_name": tables}).
 		OrderBy("ordinal_position")
+	if driver == "mysql" {
+		// Every MySQL primary key is named PRIMARY, so the constraint name
+		// alone cannot be joined on.
+		q = b.Select("table_name, column_name").
+			From("INFORMATION_SCHEMA.KEY_COLUMN_USAGE").
+			Where("table_schema = DATABASE() AND constraint_name = 'PRIMARY'").
+			Where(squirrel.Eq{"table_name": tables}).
+			OrderBy("ordinal_position")
+	}
 
 	rows, err := q.Query()
 	if err != nil {
-		return []string{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	res := []string{}
+	res := map[string][]string{}
 	for rows.Next() {
-		var s string
-		rows.Scan(&s)
-		res = append(res, s)
+		var tbl, col string
+		if err := rows.Scan(&tbl, &col); err != nil {
+			return nil, err
+		}
+		res[tbl] = append(res[tbl], col)
 	}
-	return res, nil
+	return res, rows.Err()
 }
 
-func autoincrementKey(tbl, pk string, b squirrel.StatementBuilderType) bool {
-	q := b.Select("COUNT(*)").
-		From("INFORMATION_SCHEMA.COLUMNS").
-		Where("TABLE_NAME = ? AND COLUMN_NAME = ? AND EXTRA = 'auto_increment'", tbl, pk)
-	var num int
-	if err := q.Scan(&num); err != nil {
-		panic(err)
+// uniqueKeys returns the columns of each unique constraint of tables, in
+// constraint order. Unique indexes that are not constraints are not found.
+func uniqueKeys(tables []string, b squirrel.StatementBuilderType, driver, schema string) (map[string][][]string, error) {
+	// MySQL constraint names are only unique within a table, so the table
+	// is joined on as well.
+	q := b.Select("table_name, constraint_name, column_name").
+		From("INFORMATION_SCHEMA.KEY_COLUMN_USAGE AS c").
+		LeftJoin("INFORMATION_SCHEMA.TABLE_CONSTRAINTS AS t USING(constraint_schema, constraint_name, table_name)").
+		Where("t.constraint_type = 'UNIQUE'").
+		Where(squirrel.Eq{"t.table_name": tables}).
+		OrderBy("table_name, constraint_name, ordinal_position")
+	if driver == "mysql" {
+		q = q.Where("t.table_schema = DATABASE()")
+	} else {
+		q = q.Where("t.table_schema = ?", schema)
+	}
+
+	rows, err := q.Query()
+	if err != nil {
+		return nil, err
 	}
-	return num > 0
+	defer rows.Close()
+
+	res := map[string][][]string{}
+	last := ""
+	for rows.Next() {
+		var tbl, name, col string
+		if err := rows.Scan(&tbl, &name, &col); err != nil {
+			return nil, err
+		}
+		u := res[tbl]
+		if name != last || len(u) == 0 {
+			u = append(u, []string{})
+			last = name
+		}
+		u[len(u)-1] = append(u[len(u)-1], col)
+		res[tbl] = u
+	}
+	return res, rows.Err()
 }
 
-func sequentialKey(tbl, pk string, b squirrel.StatementBuilderType) bool {
+// sequences returns the names of the sequences of a schema.
+func sequences(b squirrel.StatementBuilderType, schema string) (map[string]bool, error) {
+	rows, err := b.Select("sequence_name").
+		From("INFORMATION_SCHEMA.SEQUENCES").
+		Where("sequence_schema = ?", schema).
+		Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		res[name] = true
+	}
+	return res, rows.Err()
+}
+
+// sequenceName returns the name Postgres gives the sequence of a SERIAL
+// column, truncating the table and column names to fit.
+func sequenceName(tbl, pk string) string {
 	tlen := 58
 
 	stbl := tbl
@@ -377,55 +1912,162 @@ func sequentialKey(tbl, pk string, b squirrel.StatementBuilderType) bool {
 	if len(pk) > left {
 		spk = pk[0:left]
 	}
-	seq := fmt.Sprintf("%s_%s_seq", stbl, spk)
-
-	q := b.Select("COUNT(*)").
-		From("INFORMATION_SCHEMA.SEQUENCES").
-		Where("sequence_name = ?", seq)
+	return fmt.Sprintf("%s_%s_seq", stbl, spk)
+}
 
-	var num int
-	if err := q.Scan(&num); err != nil {
-		panic(err)
+// warnTruncation warns when a table or column name is long enough that
+// Postgres may have truncated it, or the name of the sequence it created for
+// a SERIAL column.
+//
+// sequenceName guesses the sequence name from the table and column names, so
+// once truncation comes into play, the guess may be wrong. Querying
+// pg_get_serial_sequence is the reliable way to find the sequence.
+func warnTruncation(tbl, col string, max int) {
+	seq := len(tbl) + len(col) + len("__seq")
+	if len(tbl) < max && len(col) < max && seq <= max {
+		return
 	}
-	return num > 0
+	fmt.Fprintf(os.Stderr, "Warning: %s.%s reaches the %d byte identifier limit, so SERIAL detection may be unreliable.\n", tbl, col, max)
+	fmt.Fprintf(os.Stderr, "Verify with: SELECT pg_get_serial_sequence('%s', '%s');\n", tbl, col)
 }
 
-func structFieldMySQL(c *column, pks []string, tbl string, b squirrel.StatementBuilderType) string {
-	tpl := "%s %s `stbl:\"%s\"`"
-	gn := destutter(goName(c.Name), goName(tbl))
-	tt := goType(c.DataType)
+func structFieldMySQL(c *column, pks []string, tbl string) fieldDesc {
+	tt, ok := mysqlType(c)
+	f := fieldDesc{
+		Name:     destutter(goName(c.Name), goName(tbl)),
+		Type:     tt,
+		Column:   c.Name,
+		SQLType:  c.ColumnType,
+		Tag:      c.Name,
+		NotNull:  c.NotNull,
+		Unmapped: !ok,
+	}
 
-	tag := c.Name
 	for _, p := range pks {
 		if c.Name == p {
-			tag += ",PRIMARY_KEY"
-			if autoincrementKey(tbl, c.Name, b) {
-				tag += ",AUTO_INCREMENT"
+			f.Tag += ",PRIMARY_KEY"
+			f.Key = true
+			if c.AutoIncrement {
+				f.Tag += ",AUTO_INCREMENT"
+				f.Auto = true
 			}
 		}
 	}
+	if c.Generated {
+		f.Tag += ",READ_ONLY"
+	}
 
-	return fmt.Sprintf(tpl, gn, tt, tag)
+	return f
 }
 
-func structField(c *column, pks []string, tbl string, b squirrel.StatementBuilderType) string {
-	tpl := "%s %s `stbl:\"%s\"`"
-	gn := destutter(goName(c.Name), goName(tbl))
-	tt := goType(c.DataType)
+// mysqlType returns the Go type for a MySQL column.
+//
+// MySQL has no boolean type. BOOL columns are tinyint(1), which only the
+// column_type shows.
+func mysqlType(c *column) (string, bool) {
+	if c.ColumnType == "tinyint(1)" {
+		return "bool", true
+	}
+	return lookupType(c.DataType)
+}
 
-	tag := c.Name
+func structField(c *column, pks []string, tbl string, cfg *genConfig) fieldDesc {
+	f := pgField(c, tbl, cfg)
 	for _, p := range pks {
 		if c.Name == p {
-			tag += ",PRIMARY_KEY"
-			if sequentialKey(tbl, c.Name, b) {
-				tag += ",SERIAL"
+			f.Tag += ",PRIMARY_KEY"
+			f.Key = true
+			warnTruncation(tbl, c.Name, cfg.maxIdent)
+			// A default from a sequence is SERIAL even where the sequence
+			// name could not be guessed.
+			seq := cfg.fetched.sequences[sequenceName(tbl, c.Name)] || strings.HasPrefix(c.Default.String, "nextval(")
+			if seq {
+				f.Tag += ",SERIAL"
+				f.Auto = true
 			}
 		}
 	}
 
-	return fmt.Sprintf(tpl, gn, tt, tag)
+	return f
 }
 
+// pgField describes the struct field for a Postgres column, leaving the
+// key annotations of the tag to the caller.
+func pgField(c *column, tbl string, cfg *genConfig) fieldDesc {
+	// User defined types, like citext, are only named by udt_name.
+	key := c.DataType
+	if key == "USER-DEFINED" {
+		key = c.UDTName
+	}
+	tt, ok := lookupType(key)
+	if _, mapped := userTypes[key]; !mapped && key == "uuid" && cfg.uuids == "google" {
+		tt = "uuid.UUID"
+	}
+	if e, found := cfg.enumTypes[c.UDTName]; found && c.DataType == "USER-DEFINED" {
+		if _, mapped := userTypes[key]; !mapped {
+			tt, ok = e.Name, true
+		}
+	}
+	if cfg.postgis && isGeometry(c) {
+		tt, ok = "Geometry", true
+	}
+	if cfg.trimChar && c.DataType == "character" {
+		tt = "TrimmedString"
+	}
+	comment := ""
+	if c.DataType == "ARRAY" {
+		tt, ok = pqArrays[c.UDTName], true
+		if ut, found := userTypes[c.UDTName]; found {
+			tt = ut
+		}
+		if tt == "" {
+			tt = "pq.GenericArray"
+			comment = fmt.Sprintf("Set A to a pointer to a slice of %s elements before scanning.", strings.TrimPrefix(c.UDTName, "_"))
+		}
+	}
+	// udt_name tells apart the types data_type lumps together, like the
+	// geometry types or the element types of arrays.
+	st := c.UDTName
+	if st == "" {
+		st = c.DataType
+	}
+
+	f := fieldDesc{
+		Name:     destutter(goName(c.Name), goName(tbl)),
+		Type:     tt,
+		Column:   c.Name,
+		SQLType:  st,
+		Tag:      c.Name,
+		NotNull:  c.NotNull,
+		Unmapped: !ok,
+		Comment:  comment,
+	}
+	if c.Generated {
+		f.Tag += ",READ_ONLY"
+	}
+	cfg.decimal(&f, c)
+	return f
+}
+
+// pqArrays maps the udt_name of PostgreSQL arrays to the pq types that scan
+// them. Arrays of other types are scanned with pq.GenericArray.
+var pqArrays = map[string]string{
+	"_int2":    "pq.Int64Array",
+	"_int4":    "pq.Int64Array",
+	"_int8":    "pq.Int64Array",
+	"_float4":  "pq.Float64Array",
+	"_float8":  "pq.Float64Array",
+	"_bool":    "pq.BoolArray",
+	"_bytea":   "pq.ByteaArray",
+	"_text":    "pq.StringArray",
+	"_varchar": "pq.StringArray",
+	"_bpchar":  "pq.StringArray",
+	"_uuid":    "pq.StringArray",
+}
+
+// userTypes maps SQL types to Go types, as read from --type-map.
+var userTypes = map[string]string{}
+
 // goType takes a SQL type and returns a string containin the name of a Go type.
 //
 // The goal is not to provide an exact match for every type, but to provide a
@@ -436,46 +2078,188 @@ func structField(c *column, pks []string, tbl string, b squirrel.StatementBuilde
 //
 // The default type is string.
 func goType(sqlType string) string {
+	tt, _ := lookupType(sqlType)
+	return tt
+}
+
+// lookupType returns the Go type for a SQL type, and whether the type is
+// mapped at all. The types mapped with --type-map take precedence.
+func lookupType(sqlType string) (string, bool) {
+	if tt, ok := userTypes[sqlType]; ok {
+		return tt, true
+	}
+
 	switch sqlType {
 	case "smallint", "smallserial":
-		return "int16"
+		return "int16", true
 	case "integer", "serial":
-		return "int32"
+		return "int32", true
 	case "bigint", "bigserial":
-		return "int"
+		return "int", true
 	case "real":
-		return "float32"
+		return "float32", true
 	case "double precision":
-		return "float64"
+		return "float64", true
 	// Because we need to preserve base-10 precision.
 	case "money":
-		return "string"
+		return "string", true
 	case "text", "varchar", "char", "character", "character varying", "uuid":
-		return "string"
+		return "string", true
 	case "bytea":
-		return "[]byte"
+		return "[]byte", true
+	// Scanning into json.RawMessage keeps the document as it is, and it
+	// marshals as JSON rather than as a string.
+	case "json", "jsonb":
+		return "json.RawMessage", true
 	case "boolean":
-		return "bool"
-	case "timezone", "timezonetz", "date", "time":
-		return "time.Time"
+		return "bool", true
+	// The names Postgres reports in information_schema.columns.data_type.
+	case "timestamp without time zone", "timestamp with time zone", "date":
+		return "time.Time", true
+	case "time without time zone", "time with time zone", "time":
+		return "time.Time", true
 	case "interval":
-		return "time.Duration"
+		return "time.Duration", true
+	// MySQL
+	case "tinyint":
+		return "int8", true
+	case "mediumint", "int":
+		return "int32", true
+	case "float":
+		return "float32", true
+	case "double":
+		return "float64", true
+	// Strings keep the precision, without a dependency for a decimal type.
+	case "decimal", "numeric":
+		return "string", true
+	case "tinytext", "mediumtext", "longtext", "enum", "set":
+		return "string", true
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "[]byte", true
+	case "datetime", "timestamp":
+		return "time.Time", true
+	// Oracle and SQLite name their types in upper case. See oracleType and
+	// sqliteAffinity.
+	case "INTEGER":
+		return "int", true
+	case "NUMBER", "BINARY_DOUBLE", "REAL", "NUMERIC":
+		return "float64", true
+	case "BINARY_FLOAT":
+		return "float32", true
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR", "CLOB", "NCLOB", "TEXT":
+		return "string", true
+	case "BLOB", "RAW":
+		return "[]byte", true
+	case "DATE", "TIMESTAMP", "TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITH LOCAL TIME ZONE", "DATETIME":
+		return "time.Time", true
+	}
+	return "string", false
+}
+
+// pgEnums reads the labels of the enum types in the database, leaving out
+// the system schemas. The labels are kept in their declared order.
+func pgEnums(b squirrel.StatementBuilderType) (map[string]*enumDesc, error) {
+	q := b.Select("t.typname, e.enumlabel").
+		From("pg_type AS t").
+		Join("pg_enum AS e ON e.enumtypid = t.oid").
+		Join("pg_namespace AS n ON n.oid = t.typnamespace").
+		Where("n.nspname NOT IN ('pg_catalog', 'information_schema')").
+		OrderBy("t.typname, e.enumsortorder")
+	rows, err := q.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enums := map[string]*enumDesc{}
+	for rows.Next() {
+		var typ, label string
+		if err := rows.Scan(&typ, &label); err != nil {
+			return nil, err
+		}
+		e := enums[typ]
+		if e == nil {
+			e = &enumDesc{Name: goName(typ), Type: typ}
+			enums[typ] = e
+		}
+		e.Values = append(e.Values, enumValue{Name: e.Name + goName(label), Label: label})
 	}
-	return "string"
+	return enums, rows.Err()
+}
+
+// isGeometry reports whether a column holds a PostGIS type.
+//
+// PostGIS types are user defined, so only the udt_name tells them apart.
+func isGeometry(c *column) bool {
+	return c.DataType == "USER-DEFINED" && (c.UDTName == "geometry" || c.UDTName == "geography")
 }
 
 // Convert a SQL name to a Go name.
+//
+// Characters that cannot be part of a Go identifier separate words, like
+// underscores do. A name that does not start with a letter, like 2fa_enabled,
+// is prefixed with Field. The name starts with an upper case letter, so it is
+// never a Go keyword.
 func goName(sqlName string) string {
 	// This can definitely be done better.
-	goName := strings.Replace(sqlName, "_", " ", -1)
-	goName = strings.Replace(goName, ".", " ", -1)
+	goName := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, sqlName)
 	goName = strings.Title(goName)
 	goName = strings.Replace(goName, " ", "", -1)
 
+	if !startsWithLetter(goName) {
+		goName = "Field" + goName
+	}
 	return goName
 }
 
-// destutter removes a stutter prefix.
+// uniqueFieldNames numbers the fields whose columns map to the same Go name,
+// as a_b and a-b both map to AB. The first keeps the name, and the others
+// become AB2, AB3 and so on.
+func uniqueFieldNames(d *structDesc) {
+	taken := map[string]bool{}
+	for _, f := range d.Fields {
+		taken[f.Name] = true
+	}
+	first := map[string]string{}
+	for i := range d.Fields {
+		f := &d.Fields[i]
+		other, dup := first[f.Name]
+		if !dup {
+			first[f.Name] = f.Column
+			continue
+		}
+		name := f.Name
+		for n := 2; taken[name]; n++ {
+			name = fmt.Sprintf("%s%d", f.Name, n)
+		}
+		taken[name] = true
+		fmt.Fprintf(os.Stderr, "Columns %s and %s of table %s both map to the field %s. Naming the field of %s %s.\n", other, f.Column, d.TableName, f.Name, f.Column, name)
+		f.Name = name
+	}
+}
+
+func startsWithLetter(name string) bool {
+	for _, r := range name {
+		return unicode.IsLetter(r)
+	}
+	return false
+}
+
+// destutter removes a stutter prefix, unless that leaves no valid name, as
+// for the column users_2fa in the table users, or the prefix ends mid-word,
+// as for the column type in the table t.
 func destutter(str, prefix string) string {
-	return strings.TrimPrefix(str, prefix)
+	res := strings.TrimPrefix(str, prefix)
+	for _, r := range res {
+		if unicode.IsUpper(r) {
+			return res
+		}
+		break
+	}
+	return str
 }
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestLookupTypePostgresTimes(t *testing.T) {
+	for _, sqlType := range []string{
+		"timestamp without time zone",
+		"timestamp with time zone",
+		"time without time zone",
+		"time with time zone",
+		"date",
+	} {
+		tt, ok := lookupType(sqlType)
+		if !ok || tt != "time.Time" {
+			t.Errorf("Expected %s to map to time.Time, got %q (mapped: %t)", sqlType, tt, ok)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	tables, err := parseDDL(`CREATE TABLE posts (
+  id serial PRIMARY KEY,
+  deleted_at timestamp with time zone
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &genConfig{driver: "postgres", maxIdent: 63, nullStyle: "pointer", softDelete: true}
+	d := tables[0].structDesc(cfg)
+	cfg.apply(d, "postgres")
+
+	f := d.Fields[1]
+	if f.Type != "*time.Time" {
+		t.Errorf("Expected *time.Time, got %s", f.Type)
+	}
+	if !strings.HasSuffix(f.Tag, ",SOFT_DELETE") {
+		t.Errorf("Expected a SOFT_DELETE tag, got %s", f.Tag)
+	}
+}
+
+func TestStrictTimestamps(t *testing.T) {
+	tables, err := parseDDL(`CREATE TABLE events (
+  id bigserial PRIMARY KEY,
+  starts time with time zone NOT NULL,
+  created_at timestamp NOT NULL DEFAULT now(),
+  payload tsvector
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &genConfig{driver: "postgres", maxIdent: 63, strict: true}
+	d := tables[0].structDesc(cfg)
+
+	// --strict exits on the unmapped fields, so only tsvector may be one.
+	for _, f := range d.Fields {
+		if f.Unmapped != (f.Column == "payload") {
+			t.Errorf("Column %s has type %s, unmapped: %t", f.Column, f.SQLType, f.Unmapped)
+		}
+	}
+}
+
+func TestCopyFromQualifiedTable(t *testing.T) {
+	tt := template.Must(template.New("st").Funcs(funcMap).Parse(structTemplate))
+	for table, expect := range map[string]string{
+		"invoices":         `pq.CopyIn(InvoiceTable, "amount")`,
+		"billing.invoices": `pq.CopyInSchema("billing", "invoices", "amount")`,
+	} {
+		sd := &structDesc{
+			StructName: "Invoice",
+			TableName:  table,
+			Fields: []fieldDesc{
+				{Name: "Id", Type: "int", Column: "id", Tag: "id,PRIMARY_KEY,SERIAL", Key: true, Auto: true},
+				{Name: "Amount", Type: "string", Column: "amount", Tag: "amount"},
+			},
+			Key:    []string{"id"},
+			DBType: "squirrel.DBProxyBeginner",
+			Copy:   true,
+		}
+		var buf bytes.Buffer
+		if err := tt.Execute(&buf, sd); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("Expected %s for table %s", expect, table)
+		}
+	}
+}
+
+func TestJSONOmitEmpty(t *testing.T) {
+	tables, err := parseDDL(`CREATE TABLE users (
+  id serial PRIMARY KEY,
+  email_address text NOT NULL,
+  nick_name text
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &genConfig{driver: "postgres", maxIdent: 63, nullStyle: "pointer", json: true, jsonCase: "camel", omitEmpty: true}
+	d := tables[0].structDesc(cfg)
+	cfg.apply(d, "postgres")
+
+	expect := []string{
+		"Id int32 `stbl:\"id,PRIMARY_KEY,SERIAL\" json:\"id\"`",
+		"EmailAddress string `stbl:\"email_address\" json:\"emailAddress\"`",
+		"NickName *string `stbl:\"nick_name\" json:\"nickName,omitempty\"`",
+	}
+	for i, f := range d.Fields {
+		if f.String() != expect[i] {
+			t.Errorf("Expected %s, got %s", expect[i], f.String())
+		}
+	}
+}
+
+func TestFKFieldsIgnored(t *testing.T) {
+	tt := template.Must(template.New("st").Funcs(funcMap).Parse(structTemplate))
+	sd := &structDesc{
+		StructName: "Order",
+		TableName:  "orders",
+		Fields: []fieldDesc{
+			{Name: "Id", Type: "int", Column: "id", Tag: "id,PRIMARY_KEY", Key: true},
+			{Name: "UserId", Type: "int", Column: "user_id", Tag: "user_id", FK: &fkDesc{Table: "users", Column: "id"}},
+		},
+		Key:      []string{"id"},
+		DBType:   "squirrel.DBProxyBeginner",
+		FKFields: []fkField{{Name: "User", Type: "User"}},
+	}
+	var buf bytes.Buffer
+	if err := tt.Execute(&buf, sd); err != nil {
+		t.Fatal(err)
+	}
+	// Without the tag, a naming strategy would make it a column.
+	if expect := "User *User `stbl:\"-\"`"; !strings.Contains(buf.String(), expect) {
+		t.Errorf("Expected %s in:\n%s", expect, buf.String())
+	}
+}
+
+func TestUniqueFieldNames(t *testing.T) {
+	d := &structDesc{
+		TableName: "t",
+		Fields: []fieldDesc{
+			{Name: goName("a_b"), Column: "a_b"},
+			{Name: goName("a-b"), Column: "a-b"},
+			{Name: goName("a b"), Column: "a b"},
+			{Name: goName("a_b_2"), Column: "a_b_2"},
+			{Name: goName("c"), Column: "c"},
+		},
+	}
+	uniqueFieldNames(d)
+	names := []string{}
+	for _, f := range d.Fields {
+		names = append(names, f.Name)
+	}
+	// AB2 is taken by a_b_2, so a-b skips it.
+	expect := []string{"AB", "AB3", "AB4", "AB2", "C"}
+	if strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Errorf("Expected %v, got %v", expect, names)
+	}
+}
+
+func TestDestutter(t *testing.T) {
+	for _, tt := range []struct{ table, column, expect string }{
+		{"users", "users_email", "Email"},
+		{"users", "users_2fa", "Users2fa"},
+		{"t", "type", "Type"},
+		{"user", "username", "Username"},
+		{"users", "id", "Id"},
+	} {
+		if got := destutter(goName(tt.column), goName(tt.table)); got != tt.expect {
+			t.Errorf("Expected %s for %s.%s, got %s", tt.expect, tt.table, tt.column, got)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SQLite has no INFORMATION_SCHEMA. The tables are read from sqlite_master
+// and the table_info pragma instead.
+
+// sqliteTables lists the tables of the database, leaving out SQLite's own.
+func sqliteTables(b squirrel.StatementBuilderType) ([]string, error) {
+	rows, err := b.Select("name").From("sqlite_master").
+		Where("type = 'table' AND name NOT LIKE 'sqlite_%'").
+		OrderBy("name").
+		Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+// importSQLiteTable reads a table definition with the table_info pragma.
+//
+// An INTEGER PRIMARY KEY is an alias for the rowid, which SQLite fills in
+// whether or not AUTOINCREMENT was declared, so such a key is marked
+// AUTO_INCREMENT. sqlite_sequence cannot tell this, as it only has a row
+// once something was inserted.
+func importSQLiteTable(tbl string, b squirrel.StatementBuilderType, cfg *genConfig) (*structDesc, error) {
+	// Pragma functions take no placeholders, so the name is quoted instead.
+	info := "pragma_table_info('" + strings.Replace(tbl, "'", "''", -1) + "')"
+	rows, err := b.Select(`name, type, "notnull", dflt_value, pk`).From(info).OrderBy("cid").Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, keys := []*column{}, []int{}
+	for rows.Next() {
+		c := &column{}
+		var pk int
+		if err := rows.Scan(&c.Name, &c.ColumnType, &c.NotNull, &c.Default, &pk); err != nil {
+			return nil, err
+		}
+		c.DataType = sqliteAffinity(c.ColumnType)
+		cols = append(cols, c)
+		keys = append(keys, pk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	npk := 0
+	for _, k := range keys {
+		if k > 0 {
+			npk++
+		}
+	}
+
+	// The pk column gives the position of a column in the primary key.
+	pks := make([]string, npk)
+	for i, c := range cols {
+		if keys[i] > 0 && keys[i] <= npk {
+			pks[keys[i]-1] = c.Name
+		}
+	}
+
+	ff := make([]fieldDesc, 0, len(cols))
+	for i, c := range cols {
+		f := fieldDesc{
+			Name:    destutter(goName(c.Name), goName(tbl)),
+			Type:    goType(c.DataType),
+			Column:  c.Name,
+			SQLType: c.ColumnType,
+			Tag:     c.Name,
+			NotNull: c.NotNull,
+		}
+		// The declared type is mapped first, so a user mapping can be more
+		// specific than the affinity.
+		if tt, ok := userTypes[c.ColumnType]; ok {
+			f.Type = tt
+		}
+		if keys[i] > 0 {
+			f.Tag += ",PRIMARY_KEY"
+			f.Key, f.NotNull = true, true
+			if npk == 1 && strings.EqualFold(c.ColumnType, "INTEGER") {
+				f.Tag += ",AUTO_INCREMENT"
+				f.Auto = true
+			}
+		}
+		cfg.defaultComment(&f, c)
+		ff = append(ff, f)
+	}
+
+	fks, err := foreignKeys([]string{tbl}, b, cfg.driver, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range ff {
+		ff[i].FK = fks[tbl][ff[i].Column]
+	}
+
+	return &structDesc{
+		StructName: cfg.structName(tbl),
+		TableName:  tbl,
+		Fields:     ff,
+		Key:        pks,
+	}, nil
+}
+
+// sqliteAffinity returns the type affinity SQLite gives a declared type.
+//
+// SQLite accepts any type name, and derives the affinity from it by looking
+// for substrings. The date types are kept apart, as the driver scans columns
+// declared with them into time.Time.
+func sqliteAffinity(declared string) string {
+	t := strings.ToUpper(declared)
+	switch {
+	case strings.Contains(t, "INT"):
+		return "INTEGER"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "TEXT"
+	case t == "", strings.Contains(t, "BLOB"):
+		return "BLOB"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "REAL"
+	case t == "DATE", t == "DATETIME", t == "TIMESTAMP":
+		return "DATETIME"
+	}
+	return "NUMERIC"
+}
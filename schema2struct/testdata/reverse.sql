@@ -0,0 +1,28 @@
+-- This file is automatically generated by schema2struct.
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id serial,
+	email text NOT NULL UNIQUE,
+	nick text,
+	balance double precision,
+	tags text[],
+	created_at timestamp with time zone NOT NULL,
+	updated_at timestamp with time zone,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_group (
+	group_id bigint,
+	user_id bigint,
+	mood text,
+	level smallint NOT NULL,
+	data bytea,
+	PRIMARY KEY (user_id, group_id)
+);
+
+CREATE TABLE IF NOT EXISTS event (
+	id bigserial,
+	at timestamp with time zone NOT NULL,
+	note text,
+	PRIMARY KEY (id)
+);
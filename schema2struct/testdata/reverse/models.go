@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Masterminds/structable"
+	"github.com/lib/pq"
+)
+
+// Mood is an enum type, stored as its underlying type.
+type Mood string
+
+// Stamps is embedded in other structs, and has no table of its own.
+type Stamps struct {
+	CreatedAt time.Time  `stbl:"created_at"`
+	UpdatedAt *time.Time `stbl:"updated_at"`
+}
+
+// Account is named by its tablename tag, as generated code is.
+type Account struct {
+	Id      int32           `stbl:"id,PRIMARY_KEY,SERIAL"`
+	Email   string          `stbl:"email,UNIQUE"`
+	Nick    *string         `stbl:"nick"`
+	Balance sql.NullFloat64 `stbl:"balance"`
+	Tags    pq.StringArray  `stbl:"tags"`
+	Stamps
+	Notes   string `stbl:"-"`
+	Ignored string
+
+	tableName string `tablename:"accounts"`
+	structable.Recorder
+}
+
+// UserGroup has a composite key, in the order of KEY_ORDER rather than of
+// the fields.
+type UserGroup struct {
+	GroupId int64  `stbl:"group_id,PRIMARY_KEY,KEY_ORDER=2"`
+	UserId  int64  `stbl:"user_id,PRIMARY_KEY,KEY_ORDER=1"`
+	Mood    *Mood  `stbl:"mood"`
+	Level   int16  `stbl:"level"`
+	Data    []byte `stbl:"data"`
+}
+
+// Event has an AUTO_INCREMENT key of its own width.
+type Event struct {
+	Id   int64     `stbl:"id,PRIMARY_KEY,AUTO_INCREMENT"`
+	At   time.Time `stbl:"at"`
+	Note *string   `stbl:"note"`
+}
@@ -3,9 +3,14 @@
 package structable
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	_ "github.com/mattn/go-sqlite3"
@@ -122,6 +127,16 @@ func TestStructWithPointerLoad(t *testing.T) {
 	if !CompareStringPtr(m.Genre, stringPtr("Science-Fiction")) {
 		t.Fatal("Load should instantiate nil pointers")
 	}
+
+	if _, err := db.Exec("UPDATE movies SET genre = NULL WHERE id = ?", m.Id); err != nil {
+		t.Fatalf("Sqlite Exec failed: %s", err)
+	}
+	if err := m.Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+	if m.Genre != nil {
+		t.Fatal("Load should set pointers to nil for NULL")
+	}
 }
 
 func TestStructWithPointerLoadWhere(t *testing.T) {
@@ -178,11 +193,12 @@ func TestStructWithPointerUpdate(t *testing.T) {
 		t.Fatalf("Failed Update: %s", err)
 	}
 
-	msql := new(Movie)
-	msql.loadFromSql(lastId, db)
-
-	if !CompareStringPtr(msql.Genre, stringPtr("Science-Fiction")) {
-		t.Fatal("Update should ignore nil pointers")
+	var genre sql.NullString
+	if err := db.QueryRow("SELECT genre FROM movies WHERE id=?", lastId).Scan(&genre); err != nil {
+		t.Fatalf("Sqlite QueryRow failed: %s", err)
+	}
+	if genre.Valid {
+		t.Fatal("Update should write nil pointers as NULL")
 	}
 
 	m.Genre = stringPtr("Crime Thriller")
@@ -191,10 +207,182 @@ func TestStructWithPointerUpdate(t *testing.T) {
 		t.Fatalf("Failed Update: %s", err)
 	}
 
+	msql := new(Movie)
 	msql.loadFromSql(lastId, db)
 	if !CompareStringPtr(msql.Genre, stringPtr("Crime Thriller")) {
 		t.Log("msql.Genre: %v\n", *msql.Genre)
-		t.Fatal("Update should ignore nil pointers")
+		t.Fatal("Update should dereference allocated pointers")
+	}
+}
+
+func TestTx(t *testing.T) {
+
+	db := getMoviesDb()
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql")
+
+	tx, err := r.Begin()
+	if err != nil {
+		t.Fatalf("Failed Begin: %s", err)
+	}
+	m := &Movie{Title: "Brazil", Budget: 15000000}
+	if err := tx.Bind("movies", m).Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Failed Rollback: %s", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != sql.ErrNoRows {
+		t.Fatal("Rollback should discard the insert")
+	}
+
+	tx, err = r.Begin()
+	if err != nil {
+		t.Fatalf("Failed Begin: %s", err)
+	}
+	m = &Movie{Title: "Brazil", Budget: 15000000}
+	rec := tx.Bind("movies", m)
+	if err := rec.Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed Commit: %s", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != nil {
+		t.Fatalf("Commit should keep the insert: %s", err)
+	}
+
+	if err := rec.Load(); err != ErrTxDone {
+		t.Errorf("Expected ErrTxDone after Commit, got %v", err)
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Expected ErrTxDone on a second Commit, got %v", err)
+	}
+}
+
+func TestWithTransaction(t *testing.T) {
+
+	db := getMoviesDb()
+	cache := squirrel.NewStmtCacheProxy(db)
+
+	m := &Movie{Title: "Brazil", Budget: 15000000}
+	fail := fmt.Errorf("intentional failure")
+	err := WithTransaction(cache, "mysql", func(bind func(string, interface{}) Recorder) error {
+		if err := bind("movies", m).Insert(); err != nil {
+			return err
+		}
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("Expected the error of the callback, got %v", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != sql.ErrNoRows {
+		t.Fatal("An error should roll back the insert")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected the panic to be passed on")
+			}
+		}()
+		WithTransaction(cache, "mysql", func(bind func(string, interface{}) Recorder) error {
+			bind("movies", m).Insert()
+			panic("intentional panic")
+		})
+	}()
+	if err := new(Movie).loadFromSql(m.Id, db); err != sql.ErrNoRows {
+		t.Fatal("A panic should roll back the insert")
+	}
+
+	err = WithTransaction(cache, "mysql", func(bind func(string, interface{}) Recorder) error {
+		return bind("movies", m).Insert()
+	})
+	if err != nil {
+		t.Fatalf("Failed WithTransaction: %s", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != nil {
+		t.Fatalf("Success should commit the insert: %s", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+
+	db := getMoviesDb()
+
+	m := &Movie{Title: "Alien", Budget: 11000000}
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m)
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	if _, err := db.Exec("UPDATE movies SET title = 'Aliens' WHERE id = ?", m.Id); err != nil {
+		t.Fatalf("Sqlite Exec failed: %s", err)
+	}
+	if err := r.Refresh(); err != nil {
+		t.Fatalf("Failed Refresh: %s", err)
+	}
+	if m.Title != "Aliens" || *m.Genre != "unclassifiable" {
+		t.Errorf("Expected the current row, got %s (%v)", m.Title, m.Genre)
+	}
+
+	if _, err := db.Exec("DELETE FROM movies WHERE id = ?", m.Id); err != nil {
+		t.Fatalf("Sqlite Exec failed: %s", err)
+	}
+	if err := r.Refresh(); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+
+	db := getMoviesDb()
+	m := &Movie{Id: 42, Title: "Missing"}
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m)
+
+	err := r.Load()
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected ErrNotFound wrapping sql.ErrNoRows, got %v", err)
+	}
+	if err := r.LoadWhere("title = ?", "Missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound from LoadWhere, got %v", err)
+	}
+	if err := r.Update(); err != ErrNotModified {
+		t.Errorf("Expected ErrNotModified from Update, got %v", err)
+	}
+	if err := r.Delete(); err != ErrNotModified {
+		t.Errorf("Expected ErrNotModified from Delete, got %v", err)
+	}
+}
+
+func TestInsertWith(t *testing.T) {
+
+	db := getMoviesDb()
+
+	m := &Movie{Title: "Brazil", Budget: 15000000}
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed Begin: %s", err)
+	}
+	if err := r.InsertWith(tx); err != nil {
+		t.Fatalf("Failed InsertWith: %s", err)
+	}
+	l := &Movie{Id: m.Id}
+	if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", l).LoadWith(tx); err != nil {
+		t.Fatalf("Failed LoadWith: %s", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Failed Rollback: %s", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != sql.ErrNoRows {
+		t.Fatal("Rollback should discard the insert")
+	}
+
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != nil {
+		t.Fatalf("Insert should still use the database: %s", err)
 	}
 }
 
@@ -222,3 +410,413 @@ func getMoviesDb() *sql.DB {
 	}
 	return db
 }
+
+func TestContextProxy(t *testing.T) {
+
+	db := getMoviesDb()
+
+	m := &Movie{Title: "Solaris", Budget: 1000000}
+	m.Recorder = New(NewContextProxy(db), "mysql").Bind("movies", m)
+	if err := m.InsertContext(context.Background()); err != nil {
+		t.Fatalf("Failed InsertContext: %s", err)
+	}
+
+	l := &Movie{Id: m.Id, Genre: new(string)}
+	l.Recorder = New(NewContextProxy(db), "mysql").Bind("movies", l)
+	if err := l.LoadContext(context.Background()); err != nil {
+		t.Fatalf("Failed LoadContext: %s", err)
+	}
+	if l.Title != "Solaris" {
+		t.Errorf("Expected Solaris, got %s", l.Title)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.DeleteContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err := new(Movie).loadFromSql(m.Id, db); err != nil {
+		t.Errorf("A canceled DeleteContext should not delete: %s", err)
+	}
+}
+
+type Account struct {
+	Id      int64 `stbl:"id,PRIMARY_KEY,AUTO_INCREMENT"`
+	Balance int64 `stbl:"balance"`
+	Version int64 `stbl:"version,VERSION"`
+}
+
+func TestStaleObject(t *testing.T) {
+
+	db := getMoviesDb()
+	if _, err := db.Exec("CREATE TABLE accounts (id INTEGER PRIMARY KEY AUTOINCREMENT, balance INTEGER, version INTEGER)"); err != nil {
+		t.Fatalf("Couldn't create accounts: %s", err)
+	}
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql")
+
+	a := &Account{Balance: 10}
+	if err := r.Bind("accounts", a).Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	b := &Account{Id: a.Id}
+	if err := r.Bind("accounts", b).Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+
+	a.Balance = 20
+	if err := r.Bind("accounts", a).Update(); err != nil {
+		t.Fatalf("Failed Update: %s", err)
+	}
+	if a.Version != 1 {
+		t.Errorf("Expected version 1, got %d", a.Version)
+	}
+
+	b.Balance = 30
+	if err := r.Bind("accounts", b).Update(); err != ErrStaleObject {
+		t.Errorf("Expected ErrStaleObject, got %v", err)
+	}
+}
+
+func TestUpsertStaleObject(t *testing.T) {
+
+	db := getMoviesDb()
+	if _, err := db.Exec("CREATE TABLE accounts (id INTEGER PRIMARY KEY AUTOINCREMENT, balance INTEGER, version INTEGER)"); err != nil {
+		t.Fatalf("Couldn't create accounts: %s", err)
+	}
+	cache := squirrel.NewStmtCacheProxy(db)
+
+	a := &Account{Id: 7, Balance: 10}
+	if err := New(cache, "sqlite3").Bind("accounts", a).Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	if a.Version != 0 {
+		t.Errorf("Expected version 0 after an insert, got %d", a.Version)
+	}
+	b := &Account{Id: 7, Balance: 30}
+
+	a.Balance = 20
+	if err := New(cache, "sqlite3").Bind("accounts", a).Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	if a.Version != 1 {
+		t.Errorf("Expected version 1 after an update, got %d", a.Version)
+	}
+
+	if err := New(cache, "sqlite3").Bind("accounts", b).Upsert(); err != ErrStaleObject {
+		t.Errorf("Expected ErrStaleObject, got %v", err)
+	}
+	c := &Account{Id: 7}
+	if err := New(cache, "sqlite3").Bind("accounts", c).Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+	if c.Balance != 20 || c.Version != 1 {
+		t.Errorf("Expected balance 20 at version 1, got %+v", c)
+	}
+}
+
+func TestStructWithPointerList(t *testing.T) {
+
+	db := getMoviesDb()
+	for i, title := range []string{"Alien", "Aliens", "Brazil"} {
+		m := &Movie{Title: title, Budget: float64(i)}
+		if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m).Insert(); err != nil {
+			t.Fatalf("Failed Insert: %s", err)
+		}
+	}
+
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", &Movie{})
+	recs, err := r.List(squirrel.Like{"title": "Alien%"}, OrderBy("budget DESC"), Limit(1))
+	if err != nil {
+		t.Fatalf("Failed List: %s", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("Expected 1 movie, got %d", len(recs))
+	}
+	if m := recs[0].(*Movie); m.Title != "Aliens" || *m.Genre != "unclassifiable" {
+		t.Errorf("Expected Aliens, got %s (%v)", m.Title, m.Genre)
+	}
+
+	recs, err = r.List(squirrel.Eq{"title": []string{"Alien", "Brazil"}}, OrderBy("title"))
+	if err != nil {
+		t.Fatalf("Failed List: %s", err)
+	}
+	if len(recs) != 2 || recs[1].(*Movie).Title != "Brazil" {
+		t.Fatalf("Expected Alien and Brazil, got %d movies", len(recs))
+	}
+
+	// The listed records embed a Recorder bound to them.
+	m := recs[1].(*Movie)
+	m.Budget = 42
+	if err := m.Update(); err != nil {
+		t.Fatalf("Failed Update: %s", err)
+	}
+	loaded := &Movie{Id: m.Id}
+	if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", loaded).Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+	if loaded.Budget != 42 {
+		t.Errorf("Expected the updated budget, got %v", loaded.Budget)
+	}
+
+	recs, total, err := r.Paginate(nil, 2, 2, "title")
+	if err != nil {
+		t.Fatalf("Failed Paginate: %s", err)
+	}
+	if total != 3 || len(recs) != 1 || recs[0].(*Movie).Title != "Brazil" {
+		t.Errorf("Expected Brazil of 3 movies on page 2, got %d of %d", len(recs), total)
+	}
+}
+
+type Branch struct {
+	Id       int64      `stbl:"id,PRIMARY_KEY,AUTO_INCREMENT"`
+	ParentId *int64     `stbl:"parent_id"`
+	Name     string     `stbl:"name"`
+	Deleted  *time.Time `stbl:"deleted,SOFT_DELETE"`
+}
+
+func TestLoadManyChildren(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE branches (id INTEGER PRIMARY KEY AUTOINCREMENT, parent_id INTEGER, name STRING, deleted DATETIME)"); err != nil {
+		t.Fatal(err)
+	}
+	proxy := squirrel.NewStmtCacheProxy(db)
+	root := &Branch{Name: "root"}
+	if err := New(proxy, "mysql").Bind("branches", root).Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	for _, name := range []string{"a", "b", "gone"} {
+		child := &Branch{ParentId: &root.Id, Name: name}
+		r := New(proxy, "mysql").Bind("branches", child)
+		if err := r.Insert(); err != nil {
+			t.Fatalf("Failed Insert: %s", err)
+		}
+		if name == "gone" {
+			if err := r.Delete(); err != nil {
+				t.Fatalf("Failed Delete: %s", err)
+			}
+		}
+	}
+
+	children, err := New(proxy, "mysql").Bind("branches", root).LoadMany(New(proxy, "mysql").Bind("branches", &Branch{}), "parent_id")
+	if err != nil {
+		t.Fatalf("Failed LoadMany: %s", err)
+	}
+	if len(children) != 2 {
+		t.Fatalf("Expected the 2 children that are not deleted, got %d", len(children))
+	}
+	for i, name := range []string{"a", "b"} {
+		b := children[i].(*Branch)
+		if b.Id == 0 || b.Name != name || b.ParentId == nil || *b.ParentId != root.Id {
+			t.Errorf("Expected child %s of %d, got %+v", name, root.Id, b)
+		}
+	}
+}
+
+func TestDeleteCascade(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE branches (id INTEGER PRIMARY KEY AUTOINCREMENT, parent_id INTEGER, name STRING, deleted DATETIME)"); err != nil {
+		t.Fatal(err)
+	}
+	proxy := NewContextProxy(db)
+	root := &Branch{Name: "root"}
+	r := New(proxy, "mysql").Bind("branches", root)
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	for _, parent := range []int64{root.Id, 99} {
+		child := &Branch{ParentId: &parent, Name: "child"}
+		if err := New(proxy, "mysql").Bind("branches", child).Insert(); err != nil {
+			t.Fatalf("Failed Insert: %s", err)
+		}
+	}
+	children := Relation{Table: "branches", Column: "parent_id"}
+
+	// A missing record rolls back the deletion of its children.
+	missing := New(proxy, "mysql").Bind("branches", &Branch{Id: 99})
+	if err := missing.DeleteCascade(children); err != ErrNotModified {
+		t.Errorf("Expected ErrNotModified, got %v", err)
+	}
+
+	// The record is soft deleted, and its children deleted outright.
+	if err := r.DeleteCascade(children); err != nil {
+		t.Fatalf("Failed DeleteCascade: %s", err)
+	}
+	if root.Deleted == nil {
+		t.Error("Expected the record to be marked deleted")
+	}
+	var rows, deleted int
+	if err := db.QueryRow("SELECT COUNT(*), COUNT(deleted) FROM branches").Scan(&rows, &deleted); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 || deleted != 1 {
+		t.Errorf("Expected the soft deleted record and the other child, got %d rows, %d deleted", rows, deleted)
+	}
+}
+
+func TestStructWithPointerExistsWhere(t *testing.T) {
+	db := getMoviesDb()
+	for _, title := range []string{"Alien", "Aliens"} {
+		if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", &Movie{Title: title}).Insert(); err != nil {
+			t.Fatalf("Failed Insert: %s", err)
+		}
+	}
+
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", &Movie{})
+	if has, err := r.ExistsWhere("title LIKE ?", "Alien%"); err != nil || !has {
+		t.Errorf("Expected Alien to exist, got %t, %v", has, err)
+	}
+	if has, err := r.ExistsWhere(squirrel.Eq{"title": "Brazil"}); err != nil || has {
+		t.Errorf("Expected Brazil not to exist, got %t, %v", has, err)
+	}
+}
+
+func TestStructWithPointerLoadAll(t *testing.T) {
+	db := getMoviesDb()
+	for i, title := range []string{"Alien", "Aliens", "Brazil"} {
+		m := &Movie{Title: title, Budget: float64(i)}
+		if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m).Insert(); err != nil {
+			t.Fatalf("Failed Insert: %s", err)
+		}
+	}
+
+	r := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", &Movie{})
+	var movies []Movie
+	if err := r.LoadAll(&movies, squirrel.Like{"title": "Alien%"}, OrderBy("title")); err != nil {
+		t.Fatalf("Failed LoadAll: %s", err)
+	}
+	if len(movies) != 2 || movies[0].Title != "Alien" || movies[1].Title != "Aliens" {
+		t.Errorf("Expected Alien and Aliens, got %v", movies)
+	}
+
+	ptrs := []*Movie{{Title: "Kept"}}
+	if err := r.LoadAll(&ptrs, nil, OrderBy("title")); err != nil {
+		t.Fatalf("Failed LoadAll: %s", err)
+	}
+	if len(ptrs) != 4 || ptrs[0].Title != "Kept" || ptrs[3].Title != "Brazil" {
+		t.Errorf("Expected the movies to be appended, got %d movies", len(ptrs))
+	}
+
+	var wrong []RatedMovie
+	if err := r.LoadAll(&wrong, nil); err == nil {
+		t.Error("Expected an error for a slice of another type")
+	}
+	if err := r.LoadAll(movies, nil); err == nil {
+		t.Error("Expected an error for a slice that is not a pointer")
+	}
+}
+
+// Rating is stored by name, through Scan and a Value with a pointer receiver.
+type Rating int
+
+var ratings = []string{"G", "PG", "R"}
+
+func (r *Rating) Scan(src interface{}) error {
+	var name string
+	switch v := src.(type) {
+	case []byte:
+		name = string(v)
+	case string:
+		name = v
+	}
+	for i, rating := range ratings {
+		if name == rating {
+			*r = Rating(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown rating %v", src)
+}
+
+func (r *Rating) Value() (driver.Value, error) {
+	return ratings[*r], nil
+}
+
+type RatedMovie struct {
+	Id     int64  `stbl:"id,PRIMARY_KEY,AUTO_INCREMENT"`
+	Title  string `stbl:"title"`
+	Rating Rating `stbl:"genre"`
+}
+
+func TestScannerValuer(t *testing.T) {
+
+	db := getMoviesDb()
+
+	m := &RatedMovie{Title: "Up", Rating: 1}
+	if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", m).Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+	var genre string
+	if err := db.QueryRow("SELECT genre FROM movies WHERE id=?", m.Id).Scan(&genre); err != nil {
+		t.Fatalf("Sqlite QueryRow failed: %s", err)
+	}
+	if genre != "PG" {
+		t.Errorf("Expected the rating to be stored as PG, got %s", genre)
+	}
+
+	l := &RatedMovie{Id: m.Id}
+	if err := New(squirrel.NewStmtCacheProxy(db), "mysql").Bind("movies", l).Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+	if l.Rating != 1 {
+		t.Errorf("Expected rating 1, got %d", l.Rating)
+	}
+}
+
+func TestStmtCaching(t *testing.T) {
+
+	db := getMoviesDb()
+
+	r := NewFromDB(db, "sqlite3", false)
+	if r.StmtCaching() {
+		t.Error("Expected no statement caching")
+	}
+	m := &Movie{Title: "Stalker", Budget: 1000000}
+	m.Recorder = r.Bind("movies", m)
+	if err := m.Insert(); err != nil {
+		t.Fatalf("Failed Insert: %s", err)
+	}
+
+	if err := r.WithLogger(func(string, []interface{}, time.Duration, error) {}).SetStmtCaching(true); err != nil {
+		t.Fatalf("Failed SetStmtCaching: %s", err)
+	}
+	if !r.StmtCaching() {
+		t.Error("Expected statement caching")
+	}
+	if _, ok := r.db.(*logProxy); !ok {
+		t.Error("Expected SetStmtCaching to keep the logger")
+	}
+	l := &Movie{Id: m.Id, Genre: new(string)}
+	l.Recorder = NewFromDB(db, "sqlite3", true).Bind("movies", l)
+	if err := l.Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+	if l.Title != "Stalker" {
+		t.Errorf("Expected Stalker, got %s", l.Title)
+	}
+
+	// Turning the cache off closes its statements.
+	stmt, err := r.baseDB().(*contextProxy).Prepare("SELECT COUNT(*) FROM movies")
+	if err != nil {
+		t.Fatalf("Failed Prepare: %s", err)
+	}
+	if err := r.SetStmtCaching(false); err != nil {
+		t.Fatalf("Failed SetStmtCaching: %s", err)
+	}
+	var n int
+	if err := stmt.QueryRow().Scan(&n); err == nil {
+		t.Error("Expected the cached statement to be closed")
+	}
+	if err := r.Load(); err != nil {
+		t.Fatalf("Failed Load: %s", err)
+	}
+
+	if err := New(squirrel.NewStmtCacheProxy(db), "sqlite3").SetStmtCaching(false); err == nil {
+		t.Error("Expected an error for a squirrel statement cache")
+	}
+}
@@ -15,6 +15,7 @@ The Recorder is capable of doing the following:
 	- Delete: Destroy a record in the database
 	- Has: Determine whether a given Record exists in a database
 	- LoadWhere: Load a record where certain conditions obtain.
+	- List: Load all records where certain conditions obtain.
 
 Structable is pragmatic in the sense that it allows ActiveRecord-like extension
 of the Record object to allow business logic. A Record does not *have* to be
@@ -113,6 +114,11 @@ It is also possible to emulate a DAO-type model and use the Recorder as a data
 access object and the Record as the data description object. An example of this
 method can be found in the `example/fence.go` code.
 
+The statement cache of squirrel.NewStmtCacheProxy keeps a prepared statement
+for every distinct query. Where queries vary a lot, as with WHERE clauses built
+at run time, use NewFromDB or NewDirectProxy to run statements without the
+cache, or turn it off with SetStmtCaching.
+
 The Stbl Tag
 
 The `stbl` tag is of the form:
@@ -128,11 +134,60 @@ you may need to be careful about your own naming conventions.
 `AUTO_INCREMENT` tells Structable that this field is created by the database, and should never
 be assigned during an Insert(). Aliases: SERIAL, AUTO INCREMENT
 
+`KEY_ORDER=n` gives the position of a field in a composite primary key, counting from 1. Without
+it, the key fields are in the order they are declared in.
+
+`VERSION` marks an integer field that is incremented by every update. Update() only updates the
+row if it still has the version the record was loaded with, and otherwise returns ErrStaleObject.
+This prevents lost updates without locking rows.
+
+`SOFT_DELETE` marks a *time.Time field, like `deleted_at`, that records when a row was deleted.
+Delete() sets it instead of deleting the row, and Load(), LoadWhere() and Exists() skip rows
+where it is set. ForceDelete() still deletes the row.
+
+`AUTO_CREATE` and `AUTO_UPDATE` mark time.Time (or *time.Time) fields that Structable keeps
+up to date, like `created_at` and `updated_at`. Insert() sets both to the current time in UTC,
+and Update() sets the AUTO_UPDATE fields only. InsertMany(), Upsert() and BulkUpsert() set both
+as well, but an upsert that updates an existing row keeps its AUTO_CREATE time. See
+DbRecorder.RespectExisting to keep times set by the caller.
+
+`READ_ONLY` marks a field that is loaded but never written, like a generated column or a value
+computed by a view. Insert() and Update() leave it out.
+
+A field tagged `stbl:"-"` is not a column, like a field without a tag, and an embedded struct
+tagged so is not searched for columns.
+
+Fields without a tag can still be columns by convention. After
+
+	structable.SetNamingStrategy(structable.SnakeCase)
+
+every exported field without a stbl tag is a column named by the strategy, so that FirstName
+is stored in first_name. A tag still names a field's column and sets its options, and
+`stbl:"-"` leaves a field out. Embedded fields are never named by the strategy.
+
+Fields of types that implement sql.Scanner and driver.Valuer, like enums or encrypted strings,
+are loaded and stored through those interfaces, even if only a pointer to the type implements them.
+
+Nullable columns map to pointer fields, like *string. Loading NULL sets the field to nil, and
+loading a value points the field to it. Update() writes a nil field as NULL, while Insert() leaves
+it out, so that the column gets its default value.
+
+The tagged fields of embedded structs are columns of the table too, so common columns like
+`id` and `created_at` can be declared once, in a struct that is embedded by each Record. A
+column, or a field name, must not be declared both by a Record and by a struct it embeds.
+
+Hooks
+
+A Record can act at points of its lifecycle by implementing BeforeInserter,
+AfterInserter, BeforeUpdater, AfterLoader or BeforeDeleter. An error from a
+Before hook aborts the operation. The bulk functions, like InsertMany, do not
+run hooks.
+
 Limitations
 
 Things Structable doesn't do (by design)
 
-	- Guess table or column names. You must specify these.
+	- Guess table or column names. You must specify these, or set a naming strategy.
 	- Handle relations between tables.
 	- Manage the schema.
 	- Transform complex struct fields into simple ones (that is, serialize fields).
@@ -143,9 +198,16 @@ However, Squirrel can ease many of these tasks.
 package structable
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/Masterminds/squirrel"
 )
@@ -182,6 +244,18 @@ type field struct {
 	isKey bool
 	// Is an auto increment
 	isAuto bool
+	// Position in a composite primary key, from KEY_ORDER
+	keyOrder int
+	// Records the time of a soft delete, from SOFT_DELETE
+	isSoftDelete bool
+	// Counts the updates of the row, from VERSION
+	isVersion bool
+	// Set to the current time on insert, from AUTO_CREATE
+	isAutoCreate bool
+	// Set to the current time on insert and update, from AUTO_UPDATE
+	isAutoUpdate bool
+	// Loaded but never written, from READ_ONLY
+	isReadOnly bool
 }
 
 // A Recorder is responsible for managing the persistence of a Record.
@@ -205,8 +279,27 @@ type Recorder interface {
 	// This is conceptually similar to reflect.Value.Interface().
 	Interface() interface{}
 
+	// Begin starts a transaction. Recorders bound through the returned Tx
+	// work inside the transaction until it is committed or rolled back.
+	Begin() (*Tx, error)
+
+	// Ping verifies that the database of this Recorder can be reached.
+	Ping(context.Context) error
+	// VerifyBinding checks that the columns of the bound Record exist in
+	// the bound table, and returns an error listing those that do not.
+	VerifyBinding() error
+
+	// WithContext returns a copy of this Recorder whose Load, Exists,
+	// Insert, Update, Delete and the like use the given context.
+	WithContext(context.Context) Recorder
+	// WithTimeout returns a copy of this Recorder whose Load, Exists,
+	// Insert, Update, Delete and the like each time out after the given
+	// duration.
+	WithTimeout(time.Duration) Recorder
+
 	Loader
 	Haecceity
+	Aggregator
 	Saver
 	Describer
 
@@ -223,22 +316,142 @@ type Loader interface {
 	//
 	// And then mapping the result to the currently bound Record.
 	Load() error
+	// LoadContext is Load, with a context for the query.
+	LoadContext(context.Context) error
+	// Refresh loads the Record again, returning ErrNotFound if its row is gone.
+	Refresh() error
+	// LoadWith is Load, run with the given runner, such as a *sql.Tx.
+	LoadWith(squirrel.BaseRunner) error
 	// Load by a WHERE-like clause. See Squirrel's Where(pred, args)
 	LoadWhere(interface{}, ...interface{}) error
+	// LoadMany loads the records of another table that refer to this Record
+	// through the given column.
+	LoadMany(interface{}, string) ([]interface{}, error)
+	// LoadRelated loads the record that a foreign key column of this Record
+	// refers to into another Recorder.
+	LoadRelated(string, Recorder) error
+	// List loads all records that match a WHERE-like clause, as new Records
+	// of the bound type. Options order and window the results.
+	List(interface{}, ...QueryOption) ([]interface{}, error)
+	// LoadAll loads the records that match a WHERE-like clause, like List,
+	// and appends them to a slice of the bound type.
+	LoadAll(dest interface{}, pred interface{}, opts ...QueryOption) error
+	// Paginate loads one page of the records that match a WHERE-like clause,
+	// and counts all of them.
+	Paginate(pred interface{}, page, pageSize int, orderBy string) ([]interface{}, int, error)
 }
 
 type Saver interface {
 	// Insert inserts the bound Record into the bound table.
 	Insert() error
+	// InsertContext is Insert, with a context for the statement.
+	InsertContext(context.Context) error
+	// InsertReturning inserts the bound Record, and reads the given columns
+	// back from the database.
+	InsertReturning(...string) error
+	// InsertWith is Insert, run with the given runner, such as a *sql.Tx.
+	InsertWith(squirrel.BaseRunner) error
+	// InsertColumns inserts only the given columns of the bound Record,
+	// leaving the others to their DEFAULTs.
+	InsertColumns(...string) error
 
 	// Update updates all of the fields on the bound Record based on the PRIMARY_KEY fields.
 	//
 	// Essentially, it does something like this:
 	// 	UPDATE bound_table SET every=?, field=?, but=?, keys=? WHERE primary_key=?
 	Update() error
+	// UpdateContext is Update, with a context for the statement.
+	UpdateContext(context.Context) error
+	// UpdateWith is Update, run with the given runner, such as a *sql.Tx.
+	UpdateWith(squirrel.BaseRunner) error
+
+	// UpdateColumns updates only the given columns of the bound Record, based on the PRIMARY_KEY fields.
+	UpdateColumns(...string) error
+
+	// Upsert inserts the bound Record, or updates it if a row with its
+	// PRIMARY_KEY(s) exists already.
+	Upsert() error
+	// Save inserts the bound Record if it is new, and updates it otherwise.
+	Save() error
+
+	// UpdateIf updates the bound Record like Update, but only if the given predicate also holds.
+	//
+	// It returns the number of rows affected, which is zero if the predicate did not hold.
+	UpdateIf(squirrel.Sqlizer) (int64, error)
+	// UpdateWhere sets columns to the given values in the rows that match a
+	// WHERE-like clause, which must not be empty.
+	UpdateWhere(map[string]interface{}, interface{}) (int64, error)
 
 	// Deletes a Record based on its PRIMARY_KEY(s).
 	Delete() error
+	// DeleteContext is Delete, with a context for the statement.
+	DeleteContext(context.Context) error
+	// ForceDelete deletes a Record like Delete, even if it has a SOFT_DELETE field.
+	ForceDelete() error
+	// DeleteWith is Delete, run with the given runner, such as a *sql.Tx.
+	DeleteWith(squirrel.BaseRunner) error
+	// DeleteWhere deletes the rows that match a WHERE-like clause, which must
+	// not be empty.
+	DeleteWhere(interface{}) (int64, error)
+	// DeleteAll deletes all rows of the bound table.
+	DeleteAll() (int64, error)
+
+	// DeleteCascade deletes the rows of related tables that refer to the Record,
+	// and then the Record itself, in a single transaction.
+	DeleteCascade(...Relation) error
+}
+
+// BeforeInserter is implemented by a Record that needs to act before it is
+// inserted. An error aborts the Insert.
+type BeforeInserter interface {
+	BeforeInsert() error
+}
+
+// AfterInserter is implemented by a Record that needs to act after it is inserted.
+type AfterInserter interface {
+	AfterInsert()
+}
+
+// BeforeUpdater is implemented by a Record that needs to act before it is
+// updated. An error aborts the Update.
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+// AfterLoader is implemented by a Record that needs to act after it is loaded.
+type AfterLoader interface {
+	AfterLoad()
+}
+
+// BeforeDeleter is implemented by a Record that needs to act before it is
+// deleted. An error aborts the Delete.
+type BeforeDeleter interface {
+	BeforeDelete() error
+}
+
+// Relation describes a table with rows that refer to a Record.
+//
+// Column is the column of Table that holds the primary key of the Record.
+type Relation struct {
+	Table  string
+	Column string
+}
+
+// TableDescription describes the table a Record is bound to.
+//
+// It is returned by Describe, for generic code that works on any Record.
+type TableDescription struct {
+	TableName string
+	Columns   []ColumnDescription
+}
+
+// ColumnDescription describes a column of a table, and the field of the
+// Record that holds it.
+type ColumnDescription struct {
+	Name         string
+	FieldName    string
+	IsPrimaryKey bool
+	IsAuto       bool
 }
 
 // Haecceity indicates whether a thing exists.
@@ -249,12 +462,30 @@ type Haecceity interface {
 	// Exists verifies that a thing exists and is of this type.
 	// This uses the PRIMARY_KEY to verify that a record exists.
 	Exists() (bool, error)
+	// ExistsContext is Exists, with a context for the query.
+	ExistsContext(context.Context) (bool, error)
 	// ExistsWhere verifies that a thing exists and is of the expected type.
 	// It takes a WHERE clause, and it needs to gaurantee that at least one
 	// record matches. It need not assure that *only* one item exists.
 	ExistsWhere(interface{}, ...interface{}) (bool, error)
 }
 
+// Aggregator computes single values over the rows of a table.
+type Aggregator interface {
+	// Aggregate applies SUM, AVG, MIN or MAX to a column of the rows matching
+	// a WHERE-like clause. See Squirrel's Where(pred, args)
+	Aggregate(fn, column string, pred interface{}, args ...interface{}) (sql.NullFloat64, error)
+	// Sum, Min, Max and Avg are Aggregate for one function, with 0 for NULL.
+	Sum(column string, pred interface{}) (float64, error)
+	Min(column string, pred interface{}) (float64, error)
+	Max(column string, pred interface{}) (float64, error)
+	Avg(column string, pred interface{}) (float64, error)
+	// Count counts the rows that match a WHERE-like clause, or all rows for nil.
+	Count(interface{}) (int, error)
+	// Count64 is Count, for tables that may have more rows than an int holds.
+	Count64(interface{}) (int64, error)
+}
+
 // Describer is a structable object that can describe its table structure.
 type Describer interface {
 	// Columns gets the columns on this table.
@@ -265,6 +496,10 @@ type Describer interface {
 	//
 	// This is useful to quickly generate where clauses.
 	WhereIds() map[string]interface{}
+	// Changed returns the columns that changed since the Record was loaded.
+	Changed() []string
+	// Describe returns the table and columns the Record is bound to.
+	Describe() TableDescription
 
 	// TableName returns the table name.
 	TableName() string
@@ -336,6 +571,7 @@ func ListWhere(d Recorder, fn WhereFunc) ([]Recorder, error) {
 		nv.Interface().(Recorder).Bind(d.TableName(), rec.Interface())
 
 		s := nv.Interface().(Recorder)
+		s.(*DbRecorder).placeholder = d.(*DbRecorder).placeholder
 		s.Init(d.DB(), d.Driver())
 		dest := s.FieldReferences(true)
 		rows.Scan(dest...)
@@ -345,195 +581,2058 @@ func ListWhere(d Recorder, fn WhereFunc) ([]Recorder, error) {
 	return buf, rows.Err()
 }
 
-// Implements the Recorder interface, and stores data in a DB.
-type DbRecorder struct {
-	builder *squirrel.StatementBuilderType
-	db      squirrel.DBProxyBeginner
-	table   string
-	fields  []*field
-	key     []*field
-	record  Record
-	flavor  string
-}
+// ToMap returns the values of the fields of a Record, keyed by their column names.
+//
+// The Record must be of the type bound to rec, and a nil Record stands for the
+// bound Record itself. Fields without a stbl tag are left out. The map can be
+// passed to json.Marshal, to serialize a Record with the column names of the
+// database instead of the names of its Go fields.
+func ToMap(rec Recorder, obj interface{}) (map[string]interface{}, error) {
+	if obj == nil {
+		obj = rec.Interface()
+	}
+	bound := reflect.Indirect(reflect.ValueOf(rec.Interface())).Type()
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	if v.Type() != bound {
+		return nil, fmt.Errorf("Record is a %s, but %s is bound to a %s", v.Type(), rec.TableName(), bound)
+	}
 
-func (d *DbRecorder) Interface() interface{} {
-	return d.record
+	r := new(DbRecorder)
+	r.scanFields(obj)
+	res := make(map[string]interface{}, len(r.fields))
+	for _, f := range r.fields {
+		res[f.column] = v.FieldByName(f.name).Interface()
+	}
+	return res, nil
 }
 
-// New creates a new DbRecorder.
+// InsertMap inserts a row with exactly the given columns into a table.
 //
-// (The squirrel.DBProxy interface defines the functions normal for a database connection
-// or a prepared statement cache.)
-func New(db squirrel.DBProxyBeginner, flavor string) *DbRecorder {
-	d := new(DbRecorder)
-	d.Init(db, flavor)
-	return d
-}
-
-// Init initializes a DbRecorder
-func (d *DbRecorder) Init(db squirrel.DBProxyBeginner, flavor string) {
-	b := squirrel.StatementBuilder.RunWith(db)
-	if flavor == "postgres" {
-		b = b.PlaceholderFormat(squirrel.Dollar)
+// This is useful when only some of the columns are known, for example from a
+// submitted form, and there is no populated struct to Insert.
+//
+// If proto is not nil, it is a struct tagged like any Record for the table.
+// Every key of values must then be one of its columns, and, as with Insert,
+// the returned ID is the value generated for its SERIAL or AUTO_INCREMENT
+// column. Without a proto, the returned ID is whatever LastInsertId reports,
+// or 0 on Postgres, which has no LastInsertId.
+//
+// The table name is used verbatim. DO NOT TRUST USER-SUPPLIED VALUES.
+func InsertMap(db squirrel.DBProxyBeginner, flavor, table string, values map[string]interface{}, proto Record) (int64, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("At least one column is required")
 	}
 
-	d.builder = &b
-	d.db = db
-	d.flavor = flavor
-}
-
-// TableName returns the table name of this recorder.
-func (s *DbRecorder) TableName() string {
-	return s.table
-}
+	cols := make([]string, 0, len(values))
+	for c := range values {
+		cols = append(cols, c)
+	}
+	sort.Strings(cols)
+
+	s := New(db, flavor)
+	var auto string
+	if proto != nil {
+		s.Bind(table, proto)
+		known := s.colList(true, false)
+		for _, c := range cols {
+			if !contains(known, c) {
+				return 0, fmt.Errorf("%s is not a column of table %s", c, table)
+			}
+		}
+		for _, f := range s.fields {
+			if f.isAuto {
+				auto = f.column
+			}
+		}
+	}
 
-// DB returns the database (DBProxyBeginner) for this recorder.
-func (s *DbRecorder) DB() squirrel.DBProxyBeginner {
-	return s.db
-}
+	vals := make([]interface{}, len(cols))
+	for i, c := range cols {
+		vals[i] = values[c]
+	}
+	q := s.builder.Insert(table).Columns(cols...).Values(vals...)
 
-// Builder returns the statement builder for this recorder.
-func (s *DbRecorder) Builder() *squirrel.StatementBuilderType {
-	return s.builder
-}
+	if flavor == "postgres" {
+		if auto == "" {
+			_, err := q.Exec()
+			return 0, err
+		}
+		var id int64
+		err := q.Suffix("RETURNING " + auto).QueryRow().Scan(&id)
+		return id, err
+	}
 
-// Driver returns the string name of the driver.
-func (s *DbRecorder) Driver() string {
-	return s.flavor
+	ret, err := q.Exec()
+	if err != nil {
+		return 0, err
+	}
+	id, err := ret.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("Could not get last insert ID. Did you set the db flavor? %s", err)
+	}
+	return id, nil
 }
 
-// Bind binds a DbRecorder to a Record.
+// BulkUpsert inserts many records in as few statements as possible, updating
+// any rows that already exist.
 //
-// This takes a given structable.Record and binds it to the recorder. That means
-// that the recorder will track all changes to the Record.
+// Each record must be a Recorder, and all of them must be bound to the same
+// table. A row already exists when it conflicts with an inserted row on the
+// conflictCols, which must be backed by a unique index. Every other column of
+// an existing row is overwritten by the inserted values. On MySQL, which has
+// no conflict target, the conflictCols are only left out of the update.
 //
-// The table name tells the recorder which database table to link this record
-// to. All storage operations will use that table.
-func (s *DbRecorder) Bind(tableName string, ar Record) Recorder {
-
-	// "To be is to be the value of a bound variable." - W. O. Quine
-
-	// Get the table name
-	s.table = tableName
-
-	// Get the fields
-	s.scanFields(ar)
-
-	s.record = ar
+// Rows are written in chunks that stay under the flavor's placeholder limit.
+// The chunks are not run in a transaction, so on error some of them may
+// already have been written.
+//
+// The returned count is the sum of the rows affected reported by the driver.
+func BulkUpsert(db squirrel.DBProxyBeginner, flavor string, records []interface{}, conflictCols []string) (int64, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if len(conflictCols) == 0 {
+		return 0, fmt.Errorf("At least one conflict column is required")
+	}
 
-	return Recorder(s)
-}
+	proto, cols, rows, err := bulkRows(db, flavor, records)
+	if err != nil {
+		return 0, err
+	}
 
-// Key gets the string names of the fields used as primary key.
-func (s *DbRecorder) Key() []string {
-	key := make([]string, len(s.key))
+	known := proto.colList(true, false)
+	for _, cc := range conflictCols {
+		if !contains(known, cc) {
+			return 0, fmt.Errorf("%s is not a column of table %s", cc, proto.table)
+		}
+	}
 
-	for i, f := range s.key {
-		key[i] = f.column
+	// Rows that already exist keep the time they were created.
+	updatable := []string{}
+	for _, f := range proto.fields {
+		if !f.isAutoCreate && contains(cols, f.column) {
+			updatable = append(updatable, f.column)
+		}
+	}
+	suffix, err := upsertSuffix(flavor, updatable, conflictCols)
+	if err != nil {
+		return 0, err
 	}
 
-	return key
+	return proto.insertRows(cols, rows, suffix)
 }
 
-// Load selects the record from the database and loads the values into the bound Record.
+// InsertMany inserts many records in as few statements as possible.
 //
-// Load uses the table's PRIMARY KEY(s) as the sole criterion for matching a
-// record. Essentially, it is akin to `SELECT * FROM table WHERE primary_key = ?`.
+// Each record must be a Recorder, and all of them must be bound to the same
+// table. As with Insert, AUTO_INCREMENT and SERIAL columns are left to the
+// database, but unlike Insert, the generated values are not read back.
 //
-// This modifies the Record in-place. Other than the primary key fields, any
-// other field will be overwritten by the value retrieved from the database.
-func (s *DbRecorder) Load() error {
-	whereParts := s.WhereIds()
-	dest := s.FieldReferences(false)
+// Rows are written in multi-row INSERT statements, in chunks that stay under
+// the flavor's placeholder limit. The chunks are not run in a transaction, so
+// on error some of them may already have been written.
+//
+// The returned count is the sum of the rows affected reported by the driver.
+func InsertMany(db squirrel.DBProxyBeginner, flavor string, records []interface{}) (int64, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+	proto, cols, rows, err := bulkRows(db, flavor, records)
+	if err != nil {
+		return 0, err
+	}
+	return proto.insertRows(cols, rows, "")
+}
 
-	q := s.builder.Select(s.colList(false, false)...).From(s.table).Where(whereParts)
-	err := q.QueryRow().Scan(dest...)
+// bulkRows binds each record, returning the first as a prototype along with
+// the columns and the values of every record.
+//
+// The records must be Recorders bound to the same table. Nil pointers are
+// written as NULL, so that every record has the same columns.
+func bulkRows(db squirrel.DBProxyBeginner, flavor string, records []interface{}) (*DbRecorder, []string, [][]interface{}, error) {
+	var proto *DbRecorder
+	var cols []string
+	rows := make([][]interface{}, 0, len(records))
+	for i, r := range records {
+		rec, ok := r.(Recorder)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("Record %d is not a Recorder", i)
+		}
+		s := New(db, flavor)
+		s.Bind(rec.TableName(), rec.Interface())
+		s.stamp(true)
+
+		c, vals := s.colValLists(true, false, false)
+		if proto == nil {
+			proto, cols = s, c
+		} else if s.table != proto.table || strings.Join(c, ",") != strings.Join(cols, ",") {
+			return nil, nil, nil, fmt.Errorf("Record %d does not have the columns of table %s", i, proto.table)
+		}
+		rows = append(rows, vals)
+	}
 
-	return err
+	if len(cols) == 0 {
+		return nil, nil, nil, fmt.Errorf("Table %s has no columns to insert", proto.table)
+	}
+	return proto, cols, rows, nil
 }
 
-// LoadWhere loads an object based on a WHERE clause.
-//
-// This can be used to define alternate loaders:
-//
-// 	func (s *MyStructable) LoadUuid(uuid string) error {
-// 		return s.LoadWhere("uuid = ?", uuid)
-// 	}
-//
-// This functions similarly to Load, but with the notable difference that
-// it loads the entire object (it does not skip keys used to do the lookup).
-func (s *DbRecorder) LoadWhere(pred interface{}, args ...interface{}) error {
-	dest := s.FieldReferences(true)
+// insertRows inserts rows into the table of the recorder, in chunks that stay
+// under the placeholder limit. The suffix, if any, ends each statement.
+func (s *DbRecorder) insertRows(cols []string, rows [][]interface{}, suffix string) (int64, error) {
+	per := maxPlaceholders(s.flavor) / len(cols)
+	if per == 0 {
+		return 0, fmt.Errorf("Table %s has %d columns, more than fit in one statement for %s", s.table, len(cols), s.flavor)
+	}
+	var affected int64
+	for len(rows) > 0 {
+		n := per
+		if n > len(rows) {
+			n = len(rows)
+		}
 
-	q := s.builder.Select(s.colList(true, true)...).From(s.table).Where(pred, args...)
-	err := q.QueryRow().Scan(dest...)
+		q := s.builder.Insert(s.table).Columns(cols...)
+		for _, vals := range rows[:n] {
+			q = q.Values(vals...)
+		}
+		if suffix != "" {
+			q = q.Suffix(suffix)
+		}
+		ret, err := q.Exec()
+		if err != nil {
+			return affected, err
+		}
+		if c, err := ret.RowsAffected(); err == nil {
+			affected += c
+		}
+		rows = rows[n:]
+	}
 
-	return err
+	return affected, nil
 }
 
-// Exists returns `true` if and only if there is at least one record that matches the primary keys for this Record.
+// upsertSuffix builds the clause that turns an INSERT into an upsert.
 //
-// If the primary key on the Record has no value, this will look for records with no value (or the default
-// value).
-func (s *DbRecorder) Exists() (bool, error) {
-	has := false
-	whereParts := s.WhereIds()
+// Every one of cols that is not in conflictCols is updated with the value
+// the INSERT attempted to write.
+func upsertSuffix(flavor string, cols, conflictCols []string) (string, error) {
+	set := []string{}
+	switch flavor {
+	case "postgres", "sqlite3":
+		for _, c := range cols {
+			if !contains(conflictCols, c) {
+				set = append(set, c+" = EXCLUDED."+c)
+			}
+		}
+		target := "ON CONFLICT (" + strings.Join(conflictCols, ",") + ")"
+		if len(set) == 0 {
+			return target + " DO NOTHING", nil
+		}
+		return target + " DO UPDATE SET " + strings.Join(set, ", "), nil
+	case "mysql":
+		for _, c := range cols {
+			if !contains(conflictCols, c) {
+				set = append(set, c+" = VALUES("+c+")")
+			}
+		}
+		if len(set) == 0 {
+			// MySQL has no DO NOTHING. Assigning a column to itself is the
+			// idiomatic replacement.
+			set = append(set, conflictCols[0]+" = "+conflictCols[0])
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "), nil
+	}
+	return "", fmt.Errorf("Upsert is not supported for %s", flavor)
+}
 
-	q := s.builder.Select("COUNT(*) > 0").From(s.table).Where(whereParts)
-	err := q.QueryRow().Scan(&has)
+// maxPlaceholders returns the most bound parameters a single statement may
+// have on the given flavor.
+func maxPlaceholders(flavor string) int {
+	switch flavor {
+	case "postgres", "mysql", "oracle":
+		return 65535
+	}
+	// SQLite's default, and a safe bet for anything else.
+	return 999
+}
 
-	return has, err
+// contains reports whether the list of names includes name.
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
-// ExistsWhere returns `true` if and only if there is at least one record that matches one (or multiple) conditions.
+// VerifySchema compares a Recorder with the table it is bound to.
 //
-// Conditions are expressed in the form of predicates and expected values
-// that together build a WHERE clause. See Squirrel's Where(pred, args)
-func (s *DbRecorder) ExistsWhere(pred interface{}, args ...interface{}) (bool, error) {
-	has := false
+// Every column of the bound Record is looked up in the live table's
+// INFORMATION_SCHEMA.COLUMNS. A description of each discrepancy is returned:
+// columns that are missing from the table, and columns whose SQL type cannot
+// hold the Go type of their field. An empty list means the Record matches.
+//
+// The check of types is deliberately loose. Strings, byte slices, and types
+// that implement sql.Scanner are assumed to fit any column.
+//
+// Applications can call this at startup to fail fast when their Records no
+// longer match the database. It needs a database with an INFORMATION_SCHEMA,
+// so it does not work on SQLite.
+func VerifySchema(db squirrel.DBProxyBeginner, flavor string, prototype interface{}) ([]string, error) {
+	rec, ok := prototype.(Recorder)
+	if !ok {
+		return nil, fmt.Errorf("Prototype is not a Recorder")
+	}
+	s := New(db, flavor)
+	s.Bind(rec.TableName(), rec.Interface())
+	return s.schemaProblems()
+}
 
-	q := s.builder.Select("COUNT(*) > 0").From(s.table).Where(pred, args...)
-	err := q.QueryRow().Scan(&has)
+// schemaProblems describes how the bound Record differs from the live table,
+// for VerifySchema.
+func (s *DbRecorder) schemaProblems() ([]string, error) {
+	q := s.builder.Select("column_name", "data_type").
+		From("INFORMATION_SCHEMA.COLUMNS").
+		Where(tableSchemaWhere(s.flavor, s.table))
+	rows, err := q.Query()
+	if err != nil || rows == nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return has, err
-}
+	live := map[string]string{}
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			return nil, err
+		}
+		live[name] = strings.ToLower(dataType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(live) == 0 {
+		return []string{fmt.Sprintf("table %s does not exist", s.table)}, nil
+	}
 
-// Delete deletes the record from the underlying table.
-//
-// The fields on the present record will remain set, but not saved in the database.
-func (s *DbRecorder) Delete() error {
-	wheres := s.WhereIds()
-	q := s.builder.Delete(s.table).Where(wheres)
-	_, err := q.Exec()
-	return err
+	problems := []string{}
+	t := reflect.Indirect(reflect.ValueOf(s.record)).Type()
+	for _, f := range s.fields {
+		dataType, ok := live[f.column]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %s.%s does not exist", s.table, f.column))
+			continue
+		}
+		sf, _ := t.FieldByName(f.name)
+		if !fitsColumn(sf.Type, dataType) {
+			problems = append(problems, fmt.Sprintf("column %s.%s is %s, which does not fit %s %s", s.table, f.column, dataType, f.name, sf.Type))
+		}
+	}
+	return problems, nil
 }
 
-// Insert puts a new record into the database.
+// tableSchemaWhere matches the INFORMATION_SCHEMA rows of a table.
 //
-// This operation is particularly sensitive to DB differences in cases where AUTO_INCREMENT is set
-// on a member of the Record.
-func (s *DbRecorder) Insert() error {
-	switch s.flavor {
+// A table name may be qualified with a schema. Otherwise, the table is looked
+// up in the current schema (Postgres) or database (MySQL).
+func tableSchemaWhere(flavor, table string) squirrel.Sqlizer {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return squirrel.Eq{"table_schema": table[:i], "table_name": table[i+1:]}
+	}
+	switch flavor {
 	case "postgres":
-		return s.insertPg()
-	default:
-		return s.insertStd()
+		return squirrel.And{squirrel.Expr("table_schema = current_schema()"), squirrel.Eq{"table_name": table}}
+	case "mysql":
+		return squirrel.And{squirrel.Expr("table_schema = DATABASE()"), squirrel.Eq{"table_name": table}}
 	}
+	return squirrel.Eq{"table_name": table}
 }
 
-// Insert and assume that LastInsertId() returns something.
-func (s *DbRecorder) insertStd() error {
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
 
-	cols, vals := s.colValLists(true, false)
+// fitsColumn reports whether a SQL data type can plausibly hold a Go type.
+func fitsColumn(t reflect.Type, dataType string) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	if t == timeType {
+		return strings.Contains(dataType, "date") || strings.Contains(dataType, "time")
+	}
 
-	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...)
+	has := func(parts ...string) bool {
+		for _, p := range parts {
+			if strings.Contains(dataType, p) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return has("bool", "bit", "tinyint")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return has("int", "serial", "numeric", "decimal", "number")
+	case reflect.Float32, reflect.Float64:
+		return has("real", "double", "float", "numeric", "decimal", "number", "money", "int")
+	}
+	return true
+}
 
-	ret, err := q.Exec()
+// VerifyBinding checks the bound Record against the live table, as
+// VerifySchema does, and returns an error that lists every mismatch, or nil
+// if the Record matches. Call it right after Bind, at startup, to catch a
+// Record that drifted from the schema before it fails a query:
+//
+//	user := NewUser(db, "postgres")
+//	if err := user.VerifyBinding(); err != nil {
+//		log.Fatal(err)
+//	}
+func (s *DbRecorder) VerifyBinding() error {
+	problems, err := s.schemaProblems()
 	if err != nil {
 		return err
 	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%T does not match table %s: %s", s.record, s.table, strings.Join(problems, "; "))
+	}
+	return nil
+}
 
-	for _, f := range s.fields {
+// Implements the Recorder interface, and stores data in a DB.
+type DbRecorder struct {
+	builder *squirrel.StatementBuilderType
+	db      squirrel.DBProxyBeginner
+	table   string
+	fields  []*field
+	key     []*field
+	record  Record
+	flavor  string
+	// sentinels maps columns to the value that stands for NULL in them.
+	sentinels map[string]interface{}
+	// saved maps columns to their values as last loaded or saved.
+	saved map[string]interface{}
+	// respectExisting keeps the timestamps set by the caller on insert.
+	respectExisting bool
+	// only limits the columns of an INSERT, for InsertColumns.
+	only []string
+	// ctx is the context of the methods without one, from WithContext.
+	ctx context.Context
+	// timeout limits the methods without a context, from WithTimeout.
+	timeout time.Duration
+	// placeholder overrides the placeholder format of the flavor.
+	placeholder squirrel.PlaceholderFormat
+}
+
+func (d *DbRecorder) Interface() interface{} {
+	return d.record
+}
+
+// New creates a new DbRecorder.
+//
+// (The squirrel.DBProxy interface defines the functions normal for a database connection
+// or a prepared statement cache.)
+//
+// Options, like WithPlaceholder, change the defaults of the flavor.
+func New(db squirrel.DBProxyBeginner, flavor string, opts ...Option) *DbRecorder {
+	d := new(DbRecorder)
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.Init(db, flavor)
+	return d
+}
+
+// An Option sets up a DbRecorder created by New.
+type Option func(*DbRecorder)
+
+// WithPlaceholder sets the placeholder format of the statements, which is
+// otherwise derived from the flavor: Dollar for postgres, Colon for oracle,
+// and Question for the others. The flavor still decides the rest of the SQL.
+//
+// This is for databases reached through the driver of another, like
+// CockroachDB through the postgres driver, or a proxy that expects question
+// marks:
+//
+//	r := structable.New(db, "postgres", structable.WithPlaceholder(squirrel.Question))
+//
+// Recorders derived from this one, like those bound through Begin, keep the
+// format. Functions that only take a flavor, like InsertMany, do not.
+func WithPlaceholder(f squirrel.PlaceholderFormat) Option {
+	return func(d *DbRecorder) {
+		d.placeholder = f
+	}
+}
+
+// NewFromDB creates a new DbRecorder on a database handle.
+//
+// With caching, statements are prepared once and cached, as with
+// NewContextProxy. Without it, they run directly, as with NewDirectProxy.
+// Either way, SetStmtCaching can change this later.
+func NewFromDB(db *sql.DB, flavor string, caching bool, opts ...Option) *DbRecorder {
+	if caching {
+		return New(NewContextProxy(db), flavor, opts...)
+	}
+	return New(NewDirectProxy(db), flavor, opts...)
+}
+
+// Init initializes a DbRecorder
+func (d *DbRecorder) Init(db squirrel.DBProxyBeginner, flavor string) {
+	b := squirrel.StatementBuilder.RunWith(db)
+	switch flavor {
+	case "postgres":
+		b = b.PlaceholderFormat(squirrel.Dollar)
+	case "oracle":
+		b = b.PlaceholderFormat(squirrel.Colon)
+	}
+	if d.placeholder != nil {
+		b = b.PlaceholderFormat(d.placeholder)
+	}
+
+	d.builder = &b
+	d.db = db
+	d.flavor = flavor
+}
+
+// ErrNotFound is returned by Load, LoadWhere and Refresh when there is no
+// matching row. It wraps sql.ErrNoRows, so errors.Is(err, sql.ErrNoRows) holds
+// for it too.
+var ErrNotFound = fmt.Errorf("Record not found: %w", sql.ErrNoRows)
+
+// ErrNotModified is returned by Update and Delete when their statement
+// affected no rows, typically because the row of the record does not exist.
+//
+// Note that MySQL counts only the rows that actually changed, so updating a
+// row with the values it has already returns ErrNotModified as well.
+var ErrNotModified = fmt.Errorf("No rows were affected")
+
+// ErrStaleObject is returned by Update when the record has a VERSION field,
+// and the row was updated by someone else since the record was loaded.
+var ErrStaleObject = fmt.Errorf("Record was changed since it was loaded")
+
+// ErrTxDone is returned when a Tx, or a Recorder bound through it, is used
+// after the transaction was committed or rolled back.
+var ErrTxDone = fmt.Errorf("Transaction has already been committed or rolled back")
+
+// Tx is an explicit transaction, started by Recorder.Begin.
+//
+// Unlike a single call, a Tx can span as many operations as needed, over as
+// many records as needed. Bind records through the Tx, and then call Commit
+// or Rollback exactly once:
+//
+// 	tx, err := stool.Begin()
+// 	if err != nil {
+// 		return err
+// 	}
+// 	leg := &Leg{Stool: stool.Id}
+// 	if err := tx.Bind("legs", leg).Insert(); err != nil {
+// 		tx.Rollback()
+// 		return err
+// 	}
+// 	return tx.Commit()
+type Tx struct {
+	proxy       *txProxy
+	flavor      string
+	placeholder squirrel.PlaceholderFormat
+}
+
+// Begin starts a transaction on the database of this recorder.
+func (s *DbRecorder) Begin() (*Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{proxy: &txProxy{tx: tx}, flavor: s.flavor, placeholder: s.placeholder}, nil
+}
+
+// Bind binds a record to a table, returning a Recorder that works inside this transaction.
+func (t *Tx) Bind(tableName string, ar Record) Recorder {
+	return New(t.proxy, t.flavor, WithPlaceholder(t.placeholder)).Bind(tableName, ar)
+}
+
+// Commit commits the transaction.
+func (t *Tx) Commit() error {
+	if t.proxy.done {
+		return ErrTxDone
+	}
+	t.proxy.done = true
+	return t.proxy.tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (t *Tx) Rollback() error {
+	if t.proxy.done {
+		return ErrTxDone
+	}
+	t.proxy.done = true
+	return t.proxy.tx.Rollback()
+}
+
+// WithTransaction runs fn in a transaction.
+//
+// fn gets a function that binds records to the transaction, like Tx.Bind.
+// The transaction is committed if fn returns nil, and rolled back if it
+// returns an error or panics. The error is returned, and the panic passed on,
+// after the rollback:
+//
+// 	err := structable.WithTransaction(db, "postgres", func(bind func(string, interface{}) structable.Recorder) error {
+// 		if err := bind("stools", stool).Insert(); err != nil {
+// 			return err
+// 		}
+// 		return bind("legs", &Leg{Stool: stool.Id}).Insert()
+// 	})
+func WithTransaction(db squirrel.DBProxyBeginner, flavor string, fn func(txRec func(table string, obj interface{}) Recorder) error) error {
+	tx, err := New(db, flavor).Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	bind := func(table string, obj interface{}) Recorder {
+		return tx.Bind(table, obj)
+	}
+	if err := fn(bind); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// txProxy runs statements in a transaction, and refuses to once the
+// transaction is over.
+type txProxy struct {
+	tx   *sql.Tx
+	done bool
+}
+
+func (p *txProxy) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if p.done {
+		return nil, ErrTxDone
+	}
+	return p.tx.Exec(query, args...)
+}
+
+func (p *txProxy) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if p.done {
+		return nil, ErrTxDone
+	}
+	return p.tx.Query(query, args...)
+}
+
+func (p *txProxy) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	if p.done {
+		return errRow{ErrTxDone}
+	}
+	return p.tx.QueryRow(query, args...)
+}
+
+func (p *txProxy) Prepare(query string) (*sql.Stmt, error) {
+	if p.done {
+		return nil, ErrTxDone
+	}
+	return p.tx.Prepare(query)
+}
+
+func (p *txProxy) Begin() (*sql.Tx, error) {
+	return nil, fmt.Errorf("Cannot begin a transaction inside a transaction")
+}
+
+func (p *txProxy) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if p.done {
+		return nil, ErrTxDone
+	}
+	return p.tx.ExecContext(ctx, query, args...)
+}
+
+func (p *txProxy) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if p.done {
+		return nil, ErrTxDone
+	}
+	return p.tx.QueryContext(ctx, query, args...)
+}
+
+func (p *txProxy) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	if p.done {
+		return errRow{ErrTxDone}
+	}
+	return p.tx.QueryRowContext(ctx, query, args...)
+}
+
+// NewContextProxy wraps a database in a prepared statement cache, like
+// squirrel.NewStmtCacheProxy, that also passes contexts to the database.
+//
+// The Context methods of a Recorder, like LoadContext, pass their context
+// to the database only if it takes one. With the proxy of
+// squirrel.NewStmtCacheProxy, a context can only stop a statement from
+// starting.
+func NewContextProxy(db *sql.DB) squirrel.DBProxyBeginner {
+	return &contextProxy{StmtCache: squirrel.NewStmtCache(db), db: db}
+}
+
+// NewDirectProxy wraps a database so that statements run directly on it,
+// without preparing and caching them. Contexts are passed to the database.
+//
+// A statement cache keeps a prepared statement for every distinct query, for
+// as long as the cache lives. Where queries vary a lot, as with WHERE clauses
+// built at run time, this can use up the prepared statements the server
+// allows. Running them directly avoids that, at the cost of parsing each
+// statement again.
+func NewDirectProxy(db *sql.DB) squirrel.DBProxyBeginner {
+	return &directProxy{db}
+}
+
+// directProxy runs statements on a database, without a statement cache.
+type directProxy struct {
+	*sql.DB
+}
+
+func (p *directProxy) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	return p.DB.QueryRow(query, args...)
+}
+
+func (p *directProxy) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	return p.DB.QueryRowContext(ctx, query, args...)
+}
+
+// contextProxy runs statements through a statement cache, with or without
+// a context.
+type contextProxy struct {
+	*squirrel.StmtCache
+	db *sql.DB
+}
+
+func (p *contextProxy) Begin() (*sql.Tx, error) {
+	return p.db.Begin()
+}
+
+func (p *contextProxy) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (p *contextProxy) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (p *contextProxy) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return errRow{err}
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// errRow is a row that fails to scan.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
+
+// runnerProxy runs the statements of InsertWith and the like with a runner,
+// such as a *sql.Tx.
+type runnerProxy struct {
+	squirrel.BaseRunner
+}
+
+func (p *runnerProxy) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	switch r := p.BaseRunner.(type) {
+	case squirrel.QueryRower:
+		return r.QueryRow(query, args...)
+	case interface {
+		QueryRow(string, ...interface{}) *sql.Row
+	}:
+		return r.QueryRow(query, args...)
+	}
+	return errRow{squirrel.RunnerNotQueryRunner}
+}
+
+func (p *runnerProxy) Prepare(query string) (*sql.Stmt, error) {
+	if r, ok := p.BaseRunner.(squirrel.Preparer); ok {
+		return r.Prepare(query)
+	}
+	return nil, fmt.Errorf("Runner cannot prepare statements")
+}
+
+func (p *runnerProxy) Begin() (*sql.Tx, error) {
+	return nil, fmt.Errorf("Runner cannot begin a transaction")
+}
+
+// logProxy times the statements of a database, and passes them to a Logger.
+type logProxy struct {
+	squirrel.DBProxyBeginner
+	log Logger
+}
+
+func (p *logProxy) Exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := p.DBProxyBeginner.Exec(query, args...)
+	p.log(query, args, time.Since(start), err)
+	return res, err
+}
+
+func (p *logProxy) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := p.DBProxyBeginner.Query(query, args...)
+	p.log(query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (p *logProxy) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	start := time.Now()
+	return &logRow{p.DBProxyBeginner.QueryRow(query, args...), p, query, args, start}
+}
+
+func (p *logProxy) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db, ok := p.DBProxyBeginner.(squirrel.ExecerContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return p.Exec(query, args...)
+	}
+	start := time.Now()
+	res, err := db.ExecContext(ctx, query, args...)
+	p.log(query, args, time.Since(start), err)
+	return res, err
+}
+
+func (p *logProxy) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db, ok := p.DBProxyBeginner.(squirrel.QueryerContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return p.Query(query, args...)
+	}
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query, args...)
+	p.log(query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (p *logProxy) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	db, ok := p.DBProxyBeginner.(squirrel.QueryRowerContext)
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return errRow{err}
+		}
+		return p.QueryRow(query, args...)
+	}
+	start := time.Now()
+	return &logRow{db.QueryRowContext(ctx, query, args...), p, query, args, start}
+}
+
+// logRow logs its query once it is scanned, as only then is its error known.
+type logRow struct {
+	squirrel.RowScanner
+	proxy *logProxy
+	query string
+	args  []interface{}
+	start time.Time
+}
+
+func (r *logRow) Scan(dest ...interface{}) error {
+	err := r.RowScanner.Scan(dest...)
+	r.proxy.log(r.query, r.args, time.Since(r.start), err)
+	return err
+}
+
+// with runs fn on a copy of this recorder that runs its statements with the
+// runner. The binding of this recorder is left as it was, but it learns the
+// values fn loaded or saved, for Changed.
+func (s *DbRecorder) with(runner squirrel.BaseRunner, fn func(*DbRecorder) error) error {
+	c := *s
+	c.Init(&runnerProxy{runner}, s.flavor)
+	err := fn(&c)
+	s.saved = c.saved
+	return err
+}
+
+// LoadWith loads the record like Load, but runs the query with the given
+// runner instead of the database of the recorder.
+//
+// This reuses a bound record for a single operation in a transaction:
+//
+//	tx, err := db.Begin()
+//	...
+//	err = user.LoadWith(tx)
+func (s *DbRecorder) LoadWith(runner squirrel.BaseRunner) error {
+	return s.with(runner, (*DbRecorder).Load)
+}
+
+// InsertWith inserts the record like Insert, with the given runner. See LoadWith.
+func (s *DbRecorder) InsertWith(runner squirrel.BaseRunner) error {
+	return s.with(runner, (*DbRecorder).Insert)
+}
+
+// UpdateWith updates the record like Update, with the given runner. See LoadWith.
+func (s *DbRecorder) UpdateWith(runner squirrel.BaseRunner) error {
+	return s.with(runner, (*DbRecorder).Update)
+}
+
+// DeleteWith deletes the record like Delete, with the given runner. See LoadWith.
+func (s *DbRecorder) DeleteWith(runner squirrel.BaseRunner) error {
+	return s.with(runner, (*DbRecorder).Delete)
+}
+
+// contextual reports whether the database takes a context for its statements.
+func (s *DbRecorder) contextual() bool {
+	_, execs := s.db.(squirrel.ExecerContext)
+	_, queries := s.db.(squirrel.QueryerContext)
+	return execs && queries
+}
+
+// execBuilder is a statement builder that can run with or without a context.
+type execBuilder interface {
+	Exec() (sql.Result, error)
+	ExecContext(context.Context) (sql.Result, error)
+}
+
+// exec runs a statement with the context, if the database takes one.
+// Otherwise the statement is run without it, unless the context is done.
+func (s *DbRecorder) exec(ctx context.Context, q execBuilder) (sql.Result, error) {
+	if s.contextual() {
+		return q.ExecContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return q.Exec()
+}
+
+// queryRow runs a query for one row like exec runs a statement.
+func (s *DbRecorder) queryRow(ctx context.Context, q squirrel.SelectBuilder) squirrel.RowScanner {
+	if s.contextual() {
+		return q.QueryRowContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return errRow{err}
+	}
+	return q.QueryRow()
+}
+
+// query runs a query for many rows like exec runs a statement.
+func (s *DbRecorder) query(ctx context.Context, q squirrel.SelectBuilder) (*sql.Rows, error) {
+	if s.contextual() {
+		return q.QueryContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return q.Query()
+}
+
+// Ping verifies that the database can be reached, for use in health checks.
+//
+// If the database handle has a PingContext method, like *sql.DB, it is used.
+// Otherwise, as with a statement cache, Ping runs `SELECT 1`. Either way, Ping
+// returns when ctx is done, even if the database has not answered.
+func (s *DbRecorder) Ping(ctx context.Context) error {
+	if p, ok := s.db.(interface {
+		PingContext(context.Context) error
+	}); ok {
+		return p.PingContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		var one int
+		done <- s.db.QueryRow("SELECT 1").Scan(&one)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TableName returns the table name of this recorder.
+func (s *DbRecorder) TableName() string {
+	return s.table
+}
+
+// DB returns the database (DBProxyBeginner) for this recorder.
+func (s *DbRecorder) DB() squirrel.DBProxyBeginner {
+	return s.db
+}
+
+// Builder returns the statement builder for this recorder.
+func (s *DbRecorder) Builder() *squirrel.StatementBuilderType {
+	return s.builder
+}
+
+// Driver returns the string name of the driver.
+func (s *DbRecorder) Driver() string {
+	return s.flavor
+}
+
+// WithNullSentinel makes a value of a column stand in for NULL.
+//
+// Legacy schemas sometimes store a value like -1 or 0 in a NOT NULL column to
+// mean that there is no value. With a sentinel set, loading the sentinel into
+// the column's field leaves the field nil, and storing a nil field writes the
+// sentinel. The field of the column must be a pointer.
+//
+// This returns the DbRecorder, so that it can be chained before Bind:
+//
+//	r := structable.New(db, "mysql").WithNullSentinel("parent_id", -1).Bind("nodes", node)
+func (s *DbRecorder) WithNullSentinel(column string, sentinel interface{}) *DbRecorder {
+	if s.sentinels == nil {
+		s.sentinels = map[string]interface{}{}
+	}
+	s.sentinels[column] = sentinel
+	return s
+}
+
+// Logger receives each statement a DbRecorder runs, with its arguments, how
+// long it took, and its error. See WithLogger.
+type Logger func(sql string, args []interface{}, dur time.Duration, err error)
+
+// WithLogger makes the recorder call the logger after each statement it runs,
+// such as those of Load, Insert, Update and Delete:
+//
+//	r := structable.New(db, "mysql").WithLogger(func(sql string, args []interface{}, dur time.Duration, err error) {
+//		log.Printf("%s %v took %s: %v", sql, args, dur, err)
+//	})
+//
+// A nil logger stops the logging. Without a logger, statements are run
+// directly, at no extra cost. Statements run through a Tx from Begin are not
+// logged. Like WithNullSentinel, this returns the DbRecorder, so that it can be
+// chained before Bind.
+func (s *DbRecorder) WithLogger(l Logger) *DbRecorder {
+	db := s.db
+	if p, ok := db.(*logProxy); ok {
+		db = p.DBProxyBeginner
+	}
+	if l != nil {
+		db = &logProxy{DBProxyBeginner: db, log: l}
+	}
+	s.Init(db, s.flavor)
+	return s
+}
+
+// StmtCaching reports whether the recorder prepares its statements and caches
+// them. Only a recorder on NewDirectProxy runs them directly; any other
+// database is taken to cache them, as squirrel.NewStmtCacheProxy does.
+func (s *DbRecorder) StmtCaching() bool {
+	_, direct := s.baseDB().(*directProxy)
+	return !direct
+}
+
+// SetStmtCaching turns the statement cache of the recorder on or off. With
+// the cache on, statements go through a new cache, as from NewContextProxy.
+// With it off, they run directly, as with NewDirectProxy, and the statements
+// of the old cache are closed.
+//
+// The recorder must be on one of these two proxies, as the database behind
+// other proxies, like that of squirrel.NewStmtCacheProxy, cannot be reached.
+// A logger from WithLogger is kept.
+func (s *DbRecorder) SetStmtCaching(on bool) error {
+	var db *sql.DB
+	var cache *squirrel.StmtCache
+	switch p := s.baseDB().(type) {
+	case *contextProxy:
+		db, cache = p.db, p.StmtCache
+	case *directProxy:
+		db = p.DB
+	default:
+		return fmt.Errorf("Cannot change the statement caching of %T, use NewContextProxy or NewDirectProxy", p)
+	}
+	if on == s.StmtCaching() {
+		return nil
+	}
+
+	proxy := NewDirectProxy(db)
+	if on {
+		proxy = NewContextProxy(db)
+	}
+	if p, ok := s.db.(*logProxy); ok {
+		proxy = &logProxy{DBProxyBeginner: proxy, log: p.log}
+	}
+	s.Init(proxy, s.flavor)
+	if cache != nil {
+		return cache.Clear()
+	}
+	return nil
+}
+
+// baseDB returns the database of the recorder, without the logger of
+// WithLogger.
+func (s *DbRecorder) baseDB() squirrel.DBProxyBeginner {
+	if p, ok := s.db.(*logProxy); ok {
+		return p.DBProxyBeginner
+	}
+	return s.db
+}
+
+// RespectExisting makes Insert leave alone the AUTO_CREATE and AUTO_UPDATE
+// fields that are already set, and only set those that are zero.
+//
+// This is useful to import records with their original times. Like
+// WithNullSentinel, this returns the DbRecorder, so that it can be chained
+// before Bind.
+func (s *DbRecorder) RespectExisting() *DbRecorder {
+	s.respectExisting = true
+	return s
+}
+
+// Bind binds a DbRecorder to a Record.
+//
+// This takes a given structable.Record and binds it to the recorder. That means
+// that the recorder will track all changes to the Record.
+//
+// The table name tells the recorder which database table to link this record
+// to. All storage operations will use that table.
+//
+// Bind panics if two fields of the Record, including those of embedded
+// structs, have the same name or column.
+func (s *DbRecorder) Bind(tableName string, ar Record) Recorder {
+
+	// "To be is to be the value of a bound variable." - W. O. Quine
+
+	// Get the table name
+	s.table = tableName
+
+	// Get the fields
+	s.scanFields(ar)
+
+	s.record = ar
+
+	return Recorder(s)
+}
+
+// WithContext returns a copy of the recorder that runs Load, Exists, Insert,
+// Update and Delete with the given context, as if LoadContext and the like
+// were called with it:
+//
+//	rec := user.WithContext(r.Context())
+//	err := rec.Load()
+//
+// The copy runs the other methods on the bound table with the context too,
+// like LoadWhere, List, Count, DeleteWhere, UpdateIf and Upsert.
+//
+// The copy is bound to the same Record. The recorder itself is not changed,
+// so that it can be shared while each request uses a copy with its own
+// context.
+func (s *DbRecorder) WithContext(ctx context.Context) Recorder {
+	c := *s
+	c.ctx = ctx
+	return &c
+}
+
+// WithTimeout returns a copy of the recorder that runs the methods that
+// WithContext covers with a timeout:
+//
+//	err := user.WithTimeout(2 * time.Second).Load()
+//
+// Each call gets a context of its own, which times out after d. It is derived
+// from the context set with WithContext, if there is one, and from the
+// background context otherwise. Like WithContext, the recorder itself is not
+// changed.
+func (s *DbRecorder) WithTimeout(d time.Duration) Recorder {
+	c := *s
+	c.timeout = d
+	return &c
+}
+
+// context returns the context from WithContext, or the background context,
+// with the timeout from WithTimeout. The caller must call the CancelFunc when
+// done with the context.
+func (s *DbRecorder) context() (context.Context, context.CancelFunc) {
+	ctx := s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if s.timeout > 0 {
+		return context.WithTimeout(ctx, s.timeout)
+	}
+	return ctx, func() {}
+}
+
+// Key gets the string names of the fields used as primary key.
+func (s *DbRecorder) Key() []string {
+	key := make([]string, len(s.key))
+
+	for i, f := range s.key {
+		key[i] = f.column
+	}
+
+	return key
+}
+
+// Load selects the record from the database and loads the values into the bound Record.
+//
+// Load uses the table's PRIMARY KEY(s) as the sole criterion for matching a
+// record. Essentially, it is akin to `SELECT * FROM table WHERE primary_key = ?`.
+//
+// This modifies the Record in-place. Other than the primary key fields, any
+// other field will be overwritten by the value retrieved from the database.
+func (s *DbRecorder) Load() error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.LoadContext(ctx)
+}
+
+// LoadContext loads the record like Load.
+//
+// The context is passed to the database if it takes one, like *sql.DB does.
+// Otherwise, the query is not started once the context is done.
+func (s *DbRecorder) LoadContext(ctx context.Context) error {
+	whereParts := s.WhereIds()
+	dest := s.FieldReferences(false)
+
+	q := s.builder.Select(s.colList(false, false)...).From(s.table).Where(whereParts)
+	q = s.notDeleted(q)
+	if err := s.queryRow(ctx, q).Scan(dest...); err != nil {
+		return notFound(err)
+	}
+
+	s.afterLoad()
+	return nil
+}
+
+// Refresh loads the current values of the record from the database, like Load.
+//
+// This picks up the changes made by triggers, DEFAULTs, or other writers since
+// the record was loaded or saved. If the row no longer exists, the error is
+// ErrNotFound.
+func (s *DbRecorder) Refresh() error {
+	return s.Load()
+}
+
+// notFound turns sql.ErrNoRows into ErrNotFound.
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// modified returns ErrNotModified if a statement affected no rows. Drivers
+// that do not count the rows are trusted to have affected some.
+func modified(ret sql.Result) error {
+	if n, err := ret.RowsAffected(); err == nil && n == 0 {
+		return ErrNotModified
+	}
+	return nil
+}
+
+// LoadWhere loads an object based on a WHERE clause.
+//
+// This can be used to define alternate loaders:
+//
+// 	func (s *MyStructable) LoadUuid(uuid string) error {
+// 		return s.LoadWhere("uuid = ?", uuid)
+// 	}
+//
+// This functions similarly to Load, but with the notable difference that
+// it loads the entire object (it does not skip keys used to do the lookup).
+//
+// Besides a string with its args and a map, the clause may be any
+// squirrel.Sqlizer. squirrel.And and squirrel.Or combine conditions that a
+// map cannot express, like (status = ? OR status = ?) AND active = ?:
+//
+// 	err := s.LoadWhere(squirrel.And{
+// 		squirrel.Or{squirrel.Eq{"status": "new"}, squirrel.Eq{"status": "open"}},
+// 		squirrel.Eq{"active": true},
+// 	})
+//
+// This holds for every method that takes a WHERE clause, like ExistsWhere,
+// List, Count and DeleteWhere.
+func (s *DbRecorder) LoadWhere(pred interface{}, args ...interface{}) error {
+	dest := s.FieldReferences(true)
+
+	q := s.builder.Select(s.colList(true, false)...).From(s.table).Where(s.where(pred, args...), args...)
+	q = s.notDeleted(q)
+	ctx, cancel := s.context()
+	defer cancel()
+	if err := s.queryRow(ctx, q).Scan(dest...); err != nil {
+		return notFound(err)
+	}
+
+	s.afterLoad()
+	return nil
+}
+
+// QueryOption modifies the SELECT statement of List.
+type QueryOption func(squirrel.SelectBuilder) squirrel.SelectBuilder
+
+// OrderBy orders the results of a List, as in OrderBy("created_at DESC").
+func OrderBy(exprs ...string) QueryOption {
+	return func(q squirrel.SelectBuilder) squirrel.SelectBuilder {
+		return q.OrderBy(exprs...)
+	}
+}
+
+// Limit returns at most n results from a List.
+func Limit(n uint64) QueryOption {
+	return func(q squirrel.SelectBuilder) squirrel.SelectBuilder {
+		return q.Limit(n)
+	}
+}
+
+// Offset skips the first n results of a List.
+func Offset(n uint64) QueryOption {
+	return func(q squirrel.SelectBuilder) squirrel.SelectBuilder {
+		return q.Offset(n)
+	}
+}
+
+// List loads all records that match a WHERE clause.
+//
+// The clause is anything Squirrel's Where accepts, such as a squirrel.Eq, and
+// nil matches every row. For example, this loads the ten most recent posts of
+// a user:
+//
+// 	posts, err := r.List(squirrel.Eq{"user_id": id}, OrderBy("created_at DESC"), Limit(10))
+//
+// A squirrel.Eq with a slice matches any of its values, so this loads the
+// users with the given ids:
+//
+// 	users, err := r.List(squirrel.Eq{"id": []int{1, 2, 3}})
+//
+// Each row is loaded into a new Record of the bound type, and the results are
+// pointers to those Records.
+func (s *DbRecorder) List(pred interface{}, opts ...QueryOption) ([]interface{}, error) {
+	q := s.builder.Select(s.colList(true, false)...).From(s.table)
+	if pred != nil {
+		q = q.Where(s.where(pred))
+	}
+	q = s.notDeleted(q)
+	for _, opt := range opts {
+		q = opt(q)
+	}
+	return s.loadAll(q)
+}
+
+// LoadAll loads the records that match a WHERE clause, like List, and appends
+// them to the slice dest points to, so that no type assertions are needed:
+//
+// 	var users []User
+// 	err := r.LoadAll(&users, squirrel.Eq{"active": true}, OrderBy("name"))
+//
+// The slice holds the bound type, or pointers to it, as with a *[]*User.
+func (s *DbRecorder) LoadAll(dest interface{}, pred interface{}, opts ...QueryOption) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("LoadAll needs a pointer to a slice, got %T", dest)
+	}
+	slice := v.Elem()
+	t := reflect.Indirect(reflect.ValueOf(s.record)).Type()
+	elem := slice.Type().Elem()
+	if elem != t && elem != reflect.PtrTo(t) {
+		return fmt.Errorf("Cannot load %s records into a %s", t, slice.Type())
+	}
+
+	recs, err := s.List(pred, opts...)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		r := reflect.ValueOf(rec)
+		if elem == t {
+			r = r.Elem()
+		}
+		slice = reflect.Append(slice, r)
+	}
+	v.Elem().Set(slice)
+	return nil
+}
+
+// Paginate loads one page of the records that match a WHERE clause, along with
+// the total number of matching records.
+//
+// Pages are numbered from 1, and hold pageSize records each. The clause is
+// handled like in List. If orderBy is not empty, it orders the records, as in
+// "created_at DESC". Without it, the order, and so the content of each page,
+// is up to the database.
+func (s *DbRecorder) Paginate(pred interface{}, page, pageSize int, orderBy string) ([]interface{}, int, error) {
+	if page < 1 {
+		return nil, 0, fmt.Errorf("Page must be positive, got %d", page)
+	}
+	if pageSize < 1 {
+		return nil, 0, fmt.Errorf("Page size must be positive, got %d", pageSize)
+	}
+
+	total, err := s.Count(pred)
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := []QueryOption{Limit(uint64(pageSize)), Offset(uint64((page - 1) * pageSize))}
+	if orderBy != "" {
+		opts = append(opts, OrderBy(orderBy))
+	}
+	recs, err := s.List(pred, opts...)
+	return recs, total, err
+}
+
+// loadAll runs a SELECT of all columns of the bound table, and loads every row
+// into a new Record of the bound type. A Recorder embedded in the Record is
+// set to the recorder bound to it.
+func (s *DbRecorder) loadAll(q squirrel.SelectBuilder) ([]interface{}, error) {
+	res := []interface{}{}
+	ctx, cancel := s.context()
+	defer cancel()
+	rows, err := s.query(ctx, q)
+	if err != nil || rows == nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	t := reflect.Indirect(reflect.ValueOf(s.record)).Type()
+	for rows.Next() {
+		r := New(s.db, s.flavor, WithPlaceholder(s.placeholder))
+		r.sentinels = s.sentinels
+		r.ctx, r.timeout = s.ctx, s.timeout
+		r.Bind(s.table, reflect.New(t).Interface())
+		if err := rows.Scan(r.FieldReferences(true)...); err != nil {
+			return res, err
+		}
+		r.afterLoad()
+		r.embed()
+		res = append(res, r.record)
+	}
+	return res, rows.Err()
+}
+
+// embed sets the Recorder embedded in the record, if there is one, to the
+// recorder, so that a loaded Active Record can be updated or deleted.
+func (s *DbRecorder) embed() {
+	v := reflect.Indirect(reflect.ValueOf(s.record))
+	recorder := reflect.TypeOf((*Recorder)(nil)).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.Anonymous && f.Type == recorder && v.Field(i).CanSet() {
+			v.Field(i).Set(reflect.ValueOf(Recorder(s)))
+		}
+	}
+}
+
+// afterLoad finishes loading the record, and runs its AfterLoad hook.
+func (s *DbRecorder) afterLoad() {
+	s.clearSentinels()
+	s.remember()
+	if h, ok := s.record.(AfterLoader); ok {
+		h.AfterLoad()
+	}
+}
+
+// LoadMany loads the child records that refer to this record, in a single query.
+//
+// The childPrototype is a Recorder bound to the child table, and fkColumn is
+// the column of that table which holds the primary key of this record. That is,
+// it runs something like this:
+//
+// 	SELECT child_columns FROM child_table WHERE fk_column = ?
+//
+// The children are returned as new records of the type bound to the prototype.
+// This record must have a primary key of exactly one column.
+func (s *DbRecorder) LoadMany(childPrototype interface{}, fkColumn string) ([]interface{}, error) {
+	child, ok := childPrototype.(Recorder)
+	if !ok {
+		return nil, fmt.Errorf("Child prototype is not a Recorder")
+	}
+	if len(s.key) != 1 {
+		return nil, fmt.Errorf("LoadMany needs a single column primary key, %s has %d", s.table, len(s.key))
+	}
+	if !contains(child.Columns(true), fkColumn) {
+		return nil, fmt.Errorf("%s is not a column of table %s", fkColumn, child.TableName())
+	}
+	id := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(s.key[0].name).Interface()
+
+	// The children are loaded through a bare DbRecorder, which the
+	// prototype may only embed.
+	r := New(s.db, s.flavor, WithPlaceholder(s.placeholder))
+	r.Bind(child.TableName(), child.Interface())
+	q := r.builder.Select(r.colList(true, false)...).From(r.table).Where(squirrel.Eq{fkColumn: id})
+	return r.loadAll(r.notDeleted(q))
+}
+
+// LoadRelated loads the record that a foreign key of the bound Record refers
+// to. fkColumn is the column of the bound Record that holds the primary key
+// of the target's table. Its value is copied to the primary key of the
+// target's Record, and the target is loaded:
+//
+//	author := NewUser(db, flavor)
+//	err := post.LoadRelated("author_id", author)
+//
+// The target must have a single column primary key. If the foreign key is
+// NULL, the target is not loaded and ErrNotFound is returned.
+func (s *DbRecorder) LoadRelated(fkColumn string, target Recorder) error {
+	var fk *field
+	for _, f := range s.fields {
+		if f.column == fkColumn {
+			fk = f
+		}
+	}
+	if fk == nil {
+		return fmt.Errorf("%s is not a column of table %s", fkColumn, s.table)
+	}
+	keys := []string{}
+	for _, c := range target.Describe().Columns {
+		if c.IsPrimaryKey {
+			keys = append(keys, c.FieldName)
+		}
+	}
+	if len(keys) != 1 {
+		return fmt.Errorf("LoadRelated needs a single column primary key, %s has %d", target.TableName(), len(keys))
+	}
+
+	id := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(fk.name)
+	if v, ok := id.Interface().(driver.Valuer); ok {
+		// A sql.NullInt64 and the like hold the key in their driver value.
+		dv, err := v.Value()
+		if err != nil {
+			return err
+		}
+		id = reflect.ValueOf(dv)
+	}
+	if !id.IsValid() || id.Kind() == reflect.Ptr && id.IsNil() {
+		return ErrNotFound
+	}
+	id = reflect.Indirect(id)
+
+	key := reflect.Indirect(reflect.ValueOf(target.Interface())).FieldByName(keys[0])
+	// Go converts integers to strings as runes, which is never meant here.
+	isInt := id.Kind() >= reflect.Int && id.Kind() <= reflect.Uint64
+	if !id.Type().ConvertibleTo(key.Type()) || isInt && key.Kind() == reflect.String {
+		return fmt.Errorf("Cannot set %s.%s, a %s, from %s, a %s", target.TableName(), keys[0], key.Type(), fkColumn, id.Type())
+	}
+	key.Set(id.Convert(key.Type()))
+	ctx, cancel := s.context()
+	defer cancel()
+	return target.LoadContext(ctx)
+}
+
+// Exists returns `true` if and only if there is at least one record that matches the primary keys for this Record.
+//
+// If the primary key on the Record has no value, this will look for records with no value (or the default
+// value).
+func (s *DbRecorder) Exists() (bool, error) {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.ExistsContext(ctx)
+}
+
+// ExistsContext checks for the record like Exists, with a context for the query.
+func (s *DbRecorder) ExistsContext(ctx context.Context) (bool, error) {
+	has := false
+	whereParts := s.WhereIds()
+
+	q := s.builder.Select("COUNT(*) > 0").From(s.table).Where(whereParts)
+	q = s.notDeleted(q)
+	err := s.queryRow(ctx, q).Scan(&has)
+
+	return has, err
+}
+
+// ExistsWhere returns `true` if and only if there is at least one record that matches one (or multiple) conditions.
+//
+// Conditions are expressed in the form of predicates and expected values
+// that together build a WHERE clause. See Squirrel's Where(pred, args)
+//
+// Nothing is loaded, and the database stops at the first matching row, so
+// this is cheap even where many rows match:
+//
+// 	taken, err := r.ExistsWhere("email = ?", email)
+func (s *DbRecorder) ExistsWhere(pred interface{}, args ...interface{}) (bool, error) {
+	q := s.builder.Select("1").From(s.table).Where(s.where(pred, args...), args...)
+	q = s.notDeleted(q)
+	if s.flavor == "oracle" {
+		// Oracle has no LIMIT.
+		q = q.Where("ROWNUM = 1")
+	} else {
+		q = q.Limit(1)
+	}
+
+	var one int
+	ctx, cancel := s.context()
+	defer cancel()
+	err := s.queryRow(ctx, q).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// aggregates are the functions Aggregate accepts.
+var aggregates = map[string]bool{"SUM": true, "AVG": true, "MIN": true, "MAX": true}
+
+// Aggregate returns SUM, AVG, MIN or MAX of a column over the rows that match one (or multiple) conditions.
+//
+// The column must be one of the columns tagged on the bound Record. A nil
+// predicate aggregates over the whole table. Soft-deleted rows are left out.
+// The result is NULL (Valid is false) when no rows match, or when SUM, MIN or
+// MAX only see NULL values.
+func (s *DbRecorder) Aggregate(fn, column string, pred interface{}, args ...interface{}) (sql.NullFloat64, error) {
+	var res sql.NullFloat64
+
+	fn = strings.ToUpper(fn)
+	if !aggregates[fn] {
+		return res, fmt.Errorf("Aggregate function %s is not supported", fn)
+	}
+	known := false
+	for _, f := range s.fields {
+		known = known || f.column == column
+	}
+	if !known {
+		return res, fmt.Errorf("%s is not a column of table %s", column, s.table)
+	}
+
+	q := s.builder.Select(fmt.Sprintf("%s(%s)", fn, column)).From(s.table)
+	if pred != nil {
+		q = q.Where(s.where(pred, args...), args...)
+	}
+	q = s.notDeleted(q)
+	ctx, cancel := s.context()
+	defer cancel()
+	err := s.queryRow(ctx, q).Scan(&res)
+
+	return res, err
+}
+
+// Sum returns the sum of a column over the rows that match a WHERE clause.
+//
+// It is Aggregate without arguments for the clause, and returns 0 when there
+// is nothing to sum. Use Aggregate to tell that apart from a sum of 0.
+func (s *DbRecorder) Sum(column string, pred interface{}) (float64, error) {
+	res, err := s.Aggregate("SUM", column, pred)
+	return res.Float64, err
+}
+
+// Min returns the smallest value of a column, like Sum.
+func (s *DbRecorder) Min(column string, pred interface{}) (float64, error) {
+	res, err := s.Aggregate("MIN", column, pred)
+	return res.Float64, err
+}
+
+// Max returns the largest value of a column, like Sum.
+func (s *DbRecorder) Max(column string, pred interface{}) (float64, error) {
+	res, err := s.Aggregate("MAX", column, pred)
+	return res.Float64, err
+}
+
+// Avg returns the average value of a column, like Sum.
+func (s *DbRecorder) Avg(column string, pred interface{}) (float64, error) {
+	res, err := s.Aggregate("AVG", column, pred)
+	return res.Float64, err
+}
+
+// Count returns the number of rows of the bound table that match a WHERE clause.
+//
+// The clause is anything Squirrel's Where accepts, such as a squirrel.Eq, and
+// nil counts every row. Soft deleted rows are not counted.
+func (s *DbRecorder) Count(pred interface{}) (int, error) {
+	n, err := s.Count64(pred)
+	return int(n), err
+}
+
+// Count64 returns the number of matching rows like Count, as an int64.
+func (s *DbRecorder) Count64(pred interface{}) (int64, error) {
+	var n int64
+
+	q := s.builder.Select("COUNT(*)").From(s.table)
+	if pred != nil {
+		q = q.Where(s.where(pred))
+	}
+	q = s.notDeleted(q)
+	ctx, cancel := s.context()
+	defer cancel()
+	err := s.queryRow(ctx, q).Scan(&n)
+
+	return n, err
+}
+
+// Delete deletes the record from the underlying table.
+//
+// The fields on the present record will remain set, but not saved in the database.
+//
+// If the record has a SOFT_DELETE field, the row is kept, and the field is
+// set to the current time instead, both in the database and on the record.
+//
+// If there is no row to delete, the error is ErrNotModified.
+func (s *DbRecorder) Delete() error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.DeleteContext(ctx)
+}
+
+// DeleteContext deletes the record like Delete, with a context for the statement.
+func (s *DbRecorder) DeleteContext(ctx context.Context) error {
+	if err := s.beforeDelete(); err != nil {
+		return err
+	}
+	return s.delete(ctx)
+}
+
+// delete deletes the record like DeleteContext, without the BeforeDelete hook.
+func (s *DbRecorder) delete(ctx context.Context) error {
+	wheres := s.WhereIds()
+	f := s.softDelete()
+	if f == nil {
+		ret, err := s.exec(ctx, s.builder.Delete(s.table).Where(wheres))
+		if err != nil {
+			return err
+		}
+		return modified(ret)
+	}
+
+	now := time.Now().UTC()
+	q := s.builder.Update(s.table).Set(f.column, now).Where(wheres)
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+	if err := modified(ret); err != nil {
+		return err
+	}
+	field := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(f.name)
+	if field.Type() == reflect.TypeOf(&now) {
+		field.Set(reflect.ValueOf(&now))
+	}
+	return nil
+}
+
+// ForceDelete deletes the record from the underlying table, even if it has a
+// SOFT_DELETE field.
+func (s *DbRecorder) ForceDelete() error {
+	if err := s.beforeDelete(); err != nil {
+		return err
+	}
+	q := s.builder.Delete(s.table).Where(s.WhereIds())
+	ctx, cancel := s.context()
+	defer cancel()
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+	return modified(ret)
+}
+
+// DeleteWhere deletes all rows of the bound table that match a WHERE clause,
+// and returns the number of rows deleted.
+//
+// The clause is anything Squirrel's Where accepts, such as a squirrel.Eq. To
+// guard against wiping out a table by mistake, a nil or empty clause is an
+// error. DeleteAll deletes every row.
+//
+// As with Delete, if the Record has a SOFT_DELETE field, the rows are only
+// marked deleted. The hooks of the Record are not run.
+func (s *DbRecorder) DeleteWhere(pred interface{}) (int64, error) {
+	if emptyPred(pred) {
+		return 0, fmt.Errorf("DeleteWhere needs a condition, use DeleteAll to delete all rows of %s", s.table)
+	}
+	return s.deleteWhere(pred)
+}
+
+// DeleteAll deletes all rows of the bound table, like DeleteWhere without a
+// condition.
+func (s *DbRecorder) DeleteAll() (int64, error) {
+	return s.deleteWhere(nil)
+}
+
+func (s *DbRecorder) deleteWhere(pred interface{}) (int64, error) {
+	var q execBuilder
+	if f := s.softDelete(); f != nil {
+		u := s.builder.Update(s.table).Set(f.column, time.Now().UTC()).Where(squirrel.Eq{f.column: nil})
+		if pred != nil {
+			u = u.Where(s.where(pred))
+		}
+		q = u
+	} else {
+		d := s.builder.Delete(s.table)
+		if pred != nil {
+			d = d.Where(pred)
+		}
+		q = d
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+// emptyPred reports whether a WHERE clause has no conditions, such as nil, ""
+// or an empty squirrel.Eq.
+func emptyPred(pred interface{}) bool {
+	if pred == nil {
+		return true
+	}
+	v := reflect.ValueOf(pred)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// beforeDelete runs the BeforeDelete hook of the record, if it has one.
+func (s *DbRecorder) beforeDelete() error {
+	if h, ok := s.record.(BeforeDeleter); ok {
+		return h.BeforeDelete()
+	}
+	return nil
+}
+
+// softDelete returns the SOFT_DELETE field, or nil if there is none.
+func (s *DbRecorder) softDelete() *field {
+	for _, f := range s.fields {
+		if f.isSoftDelete {
+			return f
+		}
+	}
+	return nil
+}
+
+// where returns a WHERE clause as squirrel's Where takes it: a string with its
+// args, a map, or any squirrel.Sqlizer, such as squirrel.And and squirrel.Or.
+//
+// With a SOFT_DELETE field, structable adds a condition of its own, and a
+// string is put in parentheses, so that an OR in it stays within the clause.
+func (s *DbRecorder) where(pred interface{}, args ...interface{}) interface{} {
+	if str, ok := pred.(string); ok && str != "" && s.softDelete() != nil {
+		return squirrel.Expr("("+str+")", args...)
+	}
+	return pred
+}
+
+// notDeleted restricts a query to the rows that are not soft deleted.
+func (s *DbRecorder) notDeleted(q squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if f := s.softDelete(); f != nil {
+		return q.Where(squirrel.Eq{f.column: nil})
+	}
+	return q
+}
+
+// DeleteCascade deletes the record along with the rows that refer to it.
+//
+// For each relation, the rows of the related table whose column matches the
+// record's primary key are deleted first. Then the record itself is deleted.
+// This is done in a transaction, so either everything is deleted or nothing
+// is. This is useful where the database does not declare ON DELETE CASCADE.
+//
+// Only the given relations are followed. Rows that refer to the deleted
+// rows are not deleted, so relations further down must be listed explicitly,
+// deepest first.
+//
+// The record itself is deleted like Delete: its BeforeDelete hook runs
+// first, a SOFT_DELETE field only marks it deleted, and if there is no row
+// to delete, the error is ErrNotModified and nothing is deleted. The related
+// rows are always deleted outright, as only their table is known, so a
+// SOFT_DELETE column of theirs is not used.
+//
+// The record must have a primary key of exactly one column.
+func (s *DbRecorder) DeleteCascade(relations ...Relation) error {
+	if len(s.key) != 1 {
+		return fmt.Errorf("DeleteCascade needs a single column primary key, %s has %d", s.table, len(s.key))
+	}
+	id := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(s.key[0].name).Interface()
+	if err := s.beforeDelete(); err != nil {
+		return err
+	}
+
+	tx, err := s.Begin()
+	if err != nil {
+		return err
+	}
+	c := *s
+	c.Init(tx.proxy, s.flavor)
+	ctx, cancel := s.context()
+	defer cancel()
+
+	for _, r := range relations {
+		if _, err := c.exec(ctx, c.builder.Delete(r.Table).Where(squirrel.Eq{r.Column: id})); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := c.delete(ctx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Insert puts a new record into the database.
+//
+// This operation is particularly sensitive to DB differences in cases where AUTO_INCREMENT is set
+// on a member of the Record.
+func (s *DbRecorder) Insert() error {
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.InsertContext(ctx)
+}
+
+// InsertContext inserts the record like Insert, with a context for the statement.
+func (s *DbRecorder) InsertContext(ctx context.Context) error {
+	switch s.flavor {
+	case "postgres":
+		return s.insert(ctx, s.insertPg)
+	case "oracle":
+		return s.insert(ctx, s.insertOracle)
+	default:
+		return s.insert(ctx, s.insertStd)
+	}
+}
+
+// InsertReturning inserts the record like Insert, and reads the given columns
+// back into their fields.
+//
+// This gets the values the database fills in, like a created_at column with
+// a DEFAULT, without loading the record again. Without columns, the
+// PRIMARY_KEY and AUTO_INCREMENT columns are read.
+//
+// On Postgres, this runs `INSERT ... RETURNING columns`. Other flavors have no
+// RETURNING, so only AUTO_INCREMENT columns can be read, which Insert does
+// anyway. Asking them for other columns is an error.
+func (s *DbRecorder) InsertReturning(cols ...string) error {
+	var fields []*field
+	for _, f := range s.fields {
+		if len(cols) == 0 && (f.isKey || f.isAuto) || contains(cols, f.column) {
+			fields = append(fields, f)
+		}
+	}
+	for _, c := range cols {
+		found := false
+		for _, f := range fields {
+			found = found || f.column == c
+		}
+		if !found {
+			return fmt.Errorf("%s is not a column of table %s", c, s.table)
+		}
+	}
+
+	if len(fields) == 0 {
+		return s.Insert()
+	}
+	if s.flavor == "postgres" {
+		ctx, cancel := s.context()
+		defer cancel()
+		return s.insert(ctx, func(ctx context.Context) error {
+			return s.insertReturning(ctx, fields)
+		})
+	}
+	for _, f := range fields {
+		if !f.isAuto && len(cols) > 0 {
+			return fmt.Errorf("Cannot return %s on %s, only AUTO_INCREMENT columns", f.column, s.flavor)
+		}
+	}
+	return s.Insert()
+}
+
+// InsertColumns inserts the record like Insert, but writes only the given
+// columns, so that the database fills in the others from their DEFAULTs:
+//
+//	err := rec.InsertColumns("name", "email")
+//
+// The columns must be columns Insert would write, so not AUTO_INCREMENT ones.
+// A named column with a nil pointer is written as NULL. AUTO_CREATE and
+// AUTO_UPDATE columns are written as well, as with UpdateColumns. On Postgres,
+// every field is read back from the inserted row, so the record holds the
+// defaults; elsewhere, only the AUTO_INCREMENT fields are.
+func (s *DbRecorder) InsertColumns(cols ...string) error {
+	if len(cols) == 0 {
+		return fmt.Errorf("InsertColumns needs columns, use Insert to insert all of them")
+	}
+	names, _ := s.colValLists(true, false, false)
+	for _, c := range cols {
+		if !contains(names, c) {
+			return fmt.Errorf("%s is not an insertable column of table %s", c, s.table)
+		}
+	}
+	only := append([]string{}, cols...)
+	for _, f := range s.fields {
+		if (f.isAutoCreate || f.isAutoUpdate) && !contains(only, f.column) {
+			only = append(only, f.column)
+		}
+	}
+
+	c := *s
+	c.only = only
+	err := c.Insert()
+	s.saved = c.saved
+	return err
+}
+
+// insertValues returns the columns and values of an INSERT. Nil pointers
+// are left to the database, unless InsertColumns names them.
+func (s *DbRecorder) insertValues() ([]string, []interface{}) {
+	if s.only == nil {
+		return s.colValLists(true, false, true)
+	}
+	names, vals := s.colValLists(true, false, false)
+	cols, res := []string{}, []interface{}{}
+	for i, n := range names {
+		if contains(s.only, n) {
+			cols = append(cols, n)
+			res = append(res, vals[i])
+		}
+	}
+	return cols, res
+}
+
+// insert runs an INSERT along with the hooks of the record.
+func (s *DbRecorder) insert(ctx context.Context, fn func(context.Context) error) error {
+	if h, ok := s.record.(BeforeInserter); ok {
+		if err := h.BeforeInsert(); err != nil {
+			return err
+		}
+	}
+	s.stamp(true)
+
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	s.remember()
+
+	if h, ok := s.record.(AfterInserter); ok {
+		h.AfterInsert()
+	}
+	return nil
+}
+
+// Insert and assume that LastInsertId() returns something.
+func (s *DbRecorder) insertStd(ctx context.Context) error {
+
+	cols, vals := s.insertValues()
+
+	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...)
+
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range s.fields {
 		if f.isAuto {
 			ar := reflect.Indirect(reflect.ValueOf(s.record))
 			field := ar.FieldByName(f.name)
@@ -556,18 +2655,74 @@ func (s *DbRecorder) insertStd() error {
 // insertPg runs a postgres-specific INSERT. Unlike the default (MySQL) driver,
 // this actually refreshes ALL of the fields on the Record object. We do this
 // because it is trivially easy in Postgres.
-func (s *DbRecorder) insertPg() error {
-	cols, vals := s.colValLists(true, false)
-	dest := s.FieldReferences(true)
+func (s *DbRecorder) insertPg(ctx context.Context) error {
+	return s.insertReturning(ctx, s.fields)
+}
+
+// insertReturning runs an INSERT with a RETURNING clause, which reads the
+// given fields back from the inserted row.
+func (s *DbRecorder) insertReturning(ctx context.Context, fields []*field) error {
+	cols, vals := s.insertValues()
+
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	names := make([]string, len(fields))
+	dest := make([]interface{}, len(fields))
+	for i, f := range fields {
+		names[i] = f.column
+		dest[i] = ar.FieldByName(f.name).Addr().Interface()
+	}
 	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...).
-		Suffix("RETURNING " + strings.Join(s.colList(true, false), ","))
+		Suffix("RETURNING " + strings.Join(names, ","))
+
+	if s.contextual() {
+		if err := q.QueryRowContext(ctx).Scan(dest...); err != nil {
+			return err
+		}
+		s.clearSentinels()
+		return nil
+	}
 
 	sql, vals, err := q.ToSql()
 	if err != nil {
 		return err
 	}
 
-	return s.db.QueryRow(sql, vals...).Scan(dest...)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.db.QueryRow(sql, vals...).Scan(dest...); err != nil {
+		return err
+	}
+
+	s.clearSentinels()
+	return nil
+}
+
+// insertOracle runs an Oracle-specific INSERT. Oracle has no LastInsertId, so
+// the AUTO_INCREMENT (identity) fields are read back with RETURNING ... INTO,
+// which the driver fills in through sql.Out parameters.
+func (s *DbRecorder) insertOracle(ctx context.Context) error {
+	cols, vals := s.insertValues()
+	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...)
+
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	autos, outs, marks := []string{}, []interface{}{}, []string{}
+	for _, f := range s.fields {
+		if f.isAuto {
+			autos = append(autos, f.column)
+			outs = append(outs, sql.Out{Dest: ar.FieldByName(f.name).Addr().Interface()})
+			marks = append(marks, "?")
+		}
+	}
+	if len(autos) > 0 {
+		q = q.Suffix("RETURNING "+strings.Join(autos, ",")+" INTO "+strings.Join(marks, ","), outs...)
+	}
+
+	if _, err := s.exec(ctx, q); err != nil {
+		return err
+	}
+	s.clearSentinels()
+	return nil
 }
 
 // Update updates the values on an existing entry.
@@ -575,13 +2730,492 @@ func (s *DbRecorder) insertPg() error {
 // This updates records where the Record's primary keys match the record in the
 // database. Essentially, it runs `UPDATE table SET names=values WHERE id=?`
 //
-// If no entry is found, update will NOT create (INSERT) a new record.
+// Every column other than the keys is written, and nil pointers are written as
+// NULL. UpdateColumns writes only some of the columns.
+//
+// If no entry is found, update will NOT create (INSERT) a new record, and
+// returns ErrNotModified instead.
 func (s *DbRecorder) Update() error {
-	whereParts := s.WhereIds()
-	updates := s.updateFields()
-	q := s.builder.Update(s.table).SetMap(updates).Where(whereParts)
-	_, err := q.Exec()
-	return err
+	ctx, cancel := s.context()
+	defer cancel()
+	return s.UpdateContext(ctx)
+}
+
+// UpdateContext updates the record like Update, with a context for the statement.
+func (s *DbRecorder) UpdateContext(ctx context.Context) error {
+	if err := s.beforeUpdate(); err != nil {
+		return err
+	}
+	s.stamp(false)
+	q, version := s.update(s.updateFields())
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+	if err := s.nextVersion(ret, version); err != nil {
+		return err
+	}
+	s.remember()
+	return nil
+}
+
+// update builds an UPDATE of the record that sets the given columns.
+//
+// If the record has a VERSION field, the update only matches the row with the
+// version of the record, and increments it. That field is returned, for
+// nextVersion.
+func (s *DbRecorder) update(updates map[string]interface{}) (squirrel.UpdateBuilder, *field) {
+	var version *field
+	for _, f := range s.fields {
+		if f.isVersion {
+			version = f
+			delete(updates, f.column)
+		}
+	}
+
+	q := s.builder.Update(s.table).SetMap(updates).Where(s.WhereIds())
+	if version != nil {
+		v := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(version.name).Interface()
+		q = q.Set(version.column, squirrel.Expr(version.column+" + 1")).
+			Where(squirrel.Eq{version.column: v})
+	}
+	return q, version
+}
+
+// nextVersion increments the VERSION field after an update, or returns
+// ErrStaleObject if the update matched no row. Without a VERSION field, it
+// returns ErrNotModified if the update matched no row.
+func (s *DbRecorder) nextVersion(ret sql.Result, version *field) error {
+	if version == nil {
+		return modified(ret)
+	}
+	if n, err := ret.RowsAffected(); err == nil && n == 0 {
+		return ErrStaleObject
+	}
+	f := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(version.name)
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.SetInt(f.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.SetUint(f.Uint() + 1)
+	}
+	return nil
+}
+
+// UpdateColumns updates only the given columns of an existing entry.
+//
+// This works like Update, but leaves the other columns alone, so that it does
+// not overwrite changes made to them by someone else. Together with Changed,
+// it writes only what changed since the record was loaded:
+//
+//	err := rec.UpdateColumns(rec.Changed()...)
+//
+// The columns must be columns of the record, and cannot be primary keys.
+// Without columns, nothing is done.
+func (s *DbRecorder) UpdateColumns(cols ...string) error {
+	if len(cols) == 0 {
+		return nil
+	}
+	if err := s.beforeUpdate(); err != nil {
+		return err
+	}
+	for _, c := range s.stamp(false) {
+		if !contains(cols, c) {
+			cols = append(cols, c)
+		}
+	}
+
+	names, vals := s.colValLists(false, true, false)
+	all := make(map[string]interface{}, len(names))
+	for i, n := range names {
+		all[n] = vals[i]
+	}
+	updates := map[string]interface{}{}
+	for _, c := range cols {
+		v, ok := all[c]
+		if !ok {
+			return fmt.Errorf("%s is not an updatable column of table %s", c, s.table)
+		}
+		updates[c] = v
+	}
+
+	q, version := s.update(updates)
+	ctx, cancel := s.context()
+	defer cancel()
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+	if err := s.nextVersion(ret, version); err != nil {
+		return err
+	}
+	s.remember()
+	return nil
+}
+
+// Changed returns the columns whose values differ from when the record was
+// last loaded, inserted or updated. Before any of these, every column that
+// Update would write is returned. Primary keys and READ_ONLY fields are never
+// returned.
+func (s *DbRecorder) Changed() []string {
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	changed := []string{}
+	for _, f := range s.fields {
+		if f.isKey || f.isReadOnly {
+			continue
+		}
+		old, ok := s.saved[f.column]
+		if !ok || !reflect.DeepEqual(old, savedValue(ar.FieldByName(f.name))) {
+			changed = append(changed, f.column)
+		}
+	}
+	return changed
+}
+
+// remember remembers the current values of the record, for Changed.
+func (s *DbRecorder) remember() {
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	s.saved = make(map[string]interface{}, len(s.fields))
+	for _, f := range s.fields {
+		s.saved[f.column] = savedValue(ar.FieldByName(f.name))
+	}
+}
+
+// savedValue copies the value of a field, so that later changes through a
+// pointer or to the elements of a slice do not change the copy.
+func savedValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && !v.IsNil() {
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		v = c
+	}
+	return v.Interface()
+}
+
+// stamp sets the AUTO_UPDATE fields, and the AUTO_CREATE fields when create is
+// true, to the current time. It returns the columns of the fields it set.
+func (s *DbRecorder) stamp(create bool) []string {
+	now := time.Now().UTC()
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+
+	var cols []string
+	for _, field := range s.fields {
+		if !field.isAutoUpdate && !(create && field.isAutoCreate) {
+			continue
+		}
+		f := ar.FieldByName(field.name)
+		if create && s.respectExisting && !f.IsZero() {
+			continue
+		}
+		switch f.Interface().(type) {
+		case time.Time:
+			f.Set(reflect.ValueOf(now))
+		case *time.Time:
+			t := now
+			f.Set(reflect.ValueOf(&t))
+		default:
+			continue
+		}
+		cols = append(cols, field.column)
+	}
+	return cols
+}
+
+// beforeUpdate runs the BeforeUpdate hook of the record, if it has one.
+func (s *DbRecorder) beforeUpdate() error {
+	if h, ok := s.record.(BeforeUpdater); ok {
+		return h.BeforeUpdate()
+	}
+	return nil
+}
+
+// Upsert inserts the record, or updates the existing entry with its primary key.
+//
+// This is done in a single statement, so unlike an Exists followed by an
+// Insert or Update, no other writer can sneak in between. On Postgres and
+// SQLite, it runs `INSERT ... ON CONFLICT (keys) DO UPDATE SET ...`, and on
+// MySQL `INSERT ... ON DUPLICATE KEY UPDATE ...`. Other flavors return an
+// error.
+//
+// Every column is written, including the keys, and nil pointers are written as
+// NULL. The keys and AUTO_INCREMENT columns are left out of the update, and so
+// are AUTO_CREATE columns, which are only set when a row is inserted. A new
+// record has no AUTO_INCREMENT key yet, so Upsert returns an error for a zero
+// one. Use Insert or Save for those.
+//
+// As with Update, a VERSION field is incremented when the row is updated, and
+// the row is only updated if it still has the version of the record.
+// Otherwise the error is ErrStaleObject.
+func (s *DbRecorder) Upsert() error {
+	if len(s.key) == 0 {
+		return fmt.Errorf("Upsert needs a primary key, %s has none", s.table)
+	}
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	keys := []string{}
+	for _, k := range s.key {
+		if k.isAuto && ar.FieldByName(k.name).IsZero() {
+			return fmt.Errorf("Upsert cannot write a zero %s to %s, use Insert or Save for new records", k.column, s.table)
+		}
+		keys = append(keys, k.column)
+	}
+
+	s.stamp(true)
+	cols, vals := s.colValLists(true, true, false)
+	var version *field
+	updatable := []string{}
+	for _, f := range s.fields {
+		switch {
+		case f.isVersion:
+			version = f
+		case !f.isAuto && !f.isAutoCreate && contains(cols, f.column):
+			updatable = append(updatable, f.column)
+		}
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	if version != nil {
+		if err := s.upsertVersion(ctx, cols, vals, updatable, keys, version); err != nil {
+			return err
+		}
+		s.remember()
+		return nil
+	}
+
+	suffix, err := upsertSuffix(s.flavor, updatable, keys)
+	if err != nil {
+		return err
+	}
+	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...).Suffix(suffix)
+	if _, err := s.exec(ctx, q); err != nil {
+		return err
+	}
+	s.remember()
+	return nil
+}
+
+// upsertVersion runs the upsert of a record with a VERSION field. The update
+// only applies to a row with the version of the record, and increments it.
+func (s *DbRecorder) upsertVersion(ctx context.Context, cols []string, vals []interface{}, updatable, keys []string, version *field) error {
+	v := version.column
+	// The keys are the conflict target, and are not updated.
+	var updates []string
+	for _, c := range updatable {
+		if !contains(keys, c) {
+			updates = append(updates, c)
+		}
+	}
+	q := s.builder.Insert(s.table).Columns(cols...).Values(vals...)
+	switch s.flavor {
+	case "postgres", "sqlite3":
+		set := []string{}
+		for _, c := range updates {
+			set = append(set, c+" = EXCLUDED."+c)
+		}
+		set = append(set, v+" = "+s.table+"."+v+" + 1")
+		q = q.Suffix("ON CONFLICT (" + strings.Join(keys, ",") + ") DO UPDATE SET " + strings.Join(set, ", ") +
+			" WHERE " + s.table + "." + v + " = EXCLUDED." + v)
+	case "mysql":
+		// MySQL has no WHERE for the update, so each column keeps its value
+		// unless the versions match. The version is assigned last, as MySQL
+		// assigns from left to right and the others compare the old one.
+		same := v + " = VALUES(" + v + ")"
+		set := []string{}
+		for _, c := range updates {
+			set = append(set, c+" = IF("+same+", VALUES("+c+"), "+c+")")
+		}
+		set = append(set, v+" = IF("+same+", "+v+" + 1, "+v+")")
+		q = q.Suffix("ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "))
+	default:
+		return fmt.Errorf("Upsert is not supported for %s", s.flavor)
+	}
+
+	dest := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(version.name).Addr().Interface()
+	if s.flavor == "postgres" {
+		// The returned version tells an update from an insert. No row is
+		// returned if the version did not match.
+		q = q.Suffix("RETURNING " + v)
+		var row squirrel.RowScanner
+		if s.contextual() {
+			row = q.QueryRowContext(ctx)
+		} else if err := ctx.Err(); err != nil {
+			return err
+		} else {
+			row = q.QueryRow()
+		}
+		if err := row.Scan(dest); err != sql.ErrNoRows {
+			return err
+		}
+		return ErrStaleObject
+	}
+
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return err
+	}
+	n, err := ret.RowsAffected()
+	switch {
+	case err != nil:
+		return err
+	case n == 0:
+		return ErrStaleObject
+	case s.flavor == "mysql" && n == 2:
+		// MySQL counts an updated row twice, and an inserted row once.
+		return s.nextVersion(ret, version)
+	case s.flavor == "sqlite3":
+		// SQLite cannot tell an update from an insert, so the version is
+		// read back.
+		q := s.builder.Select(v).From(s.table).Where(s.WhereIds())
+		return s.queryRow(ctx, q).Scan(dest)
+	}
+	return nil
+}
+
+// Save inserts the record if it is new, and updates it otherwise.
+//
+// A record with a single SERIAL or AUTO_INCREMENT key is new if the key is
+// zero, as the database has not assigned it yet. For other keys, Save checks
+// whether the row exists first, in a separate query. Unlike Upsert, this
+// works on every flavor, and runs the hooks of Insert or Update, but a row
+// inserted by someone else in between makes the Insert fail.
+func (s *DbRecorder) Save() error {
+	if len(s.key) == 0 {
+		return fmt.Errorf("Save needs a primary key, %s has none", s.table)
+	}
+
+	if len(s.key) == 1 && s.key[0].isAuto {
+		id := reflect.Indirect(reflect.ValueOf(s.record)).FieldByName(s.key[0].name)
+		if id.IsZero() {
+			return s.Insert()
+		}
+		return s.Update()
+	}
+
+	exists, err := s.Exists()
+	if err != nil {
+		return err
+	}
+	if exists {
+		return s.Update()
+	}
+	return s.Insert()
+}
+
+// UpdateIf updates the values on an existing entry if a predicate holds.
+//
+// This works like Update, but adds the predicate to the WHERE clause.
+// Essentially, it runs `UPDATE table SET names=values WHERE id=? AND (pred)`.
+// This makes it possible to change a record only if it is still in an
+// expected state, without another writer sneaking in between a Load and
+// an Update:
+//
+//	n, err := order.UpdateIf(squirrel.Eq{"status": "pending"})
+//
+// The number of affected rows is returned. Zero means that the predicate did
+// not hold, or that no record has the given primary key.
+func (s *DbRecorder) UpdateIf(pred squirrel.Sqlizer) (int64, error) {
+	guard, args, err := pred.ToSql()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.beforeUpdate(); err != nil {
+		return 0, err
+	}
+	s.stamp(false)
+
+	q, version := s.update(s.updateFields())
+	ctx, cancel := s.context()
+	defer cancel()
+	ret, err := s.exec(ctx, q.Where("("+guard+")", args...))
+	if err != nil {
+		return 0, err
+	}
+	n, err := ret.RowsAffected()
+	if err != nil || n == 0 {
+		return n, err
+	}
+	if err := s.nextVersion(ret, version); err != nil {
+		return n, err
+	}
+	s.remember()
+	return n, nil
+}
+
+// UpdateWhere sets columns to the given values in all rows of the bound table
+// that match a WHERE clause, and returns the number of rows updated:
+//
+//	n, err := r.UpdateWhere(map[string]interface{}{"status": "archived"}, squirrel.Lt{"created_at": cutoff})
+//
+// The columns must be columns Update would write. The clause is handled like
+// in DeleteWhere, and must not be empty. Unless they are set explicitly,
+// AUTO_UPDATE columns are set to the current time, and VERSION columns are
+// incremented. Soft deleted rows are left alone. The bound Record itself is
+// not changed, and its hooks are not run.
+func (s *DbRecorder) UpdateWhere(set map[string]interface{}, pred interface{}) (int64, error) {
+	if emptyPred(pred) {
+		return 0, fmt.Errorf("UpdateWhere needs a condition, so that it cannot update all rows of %s by mistake", s.table)
+	}
+	if len(set) == 0 {
+		return 0, fmt.Errorf("UpdateWhere needs columns to set")
+	}
+	names, _ := s.colValLists(false, true, false)
+	for c := range set {
+		if !contains(names, c) {
+			return 0, fmt.Errorf("%s is not an updatable column of table %s", c, s.table)
+		}
+	}
+
+	q := s.builder.Update(s.table).SetMap(set)
+	now := time.Now().UTC()
+	for _, f := range s.fields {
+		if _, ok := set[f.column]; ok {
+			continue
+		}
+		switch {
+		case f.isAutoUpdate:
+			q = q.Set(f.column, now)
+		case f.isVersion:
+			q = q.Set(f.column, squirrel.Expr(f.column+" + 1"))
+		}
+	}
+	q = q.Where(s.where(pred))
+	if f := s.softDelete(); f != nil {
+		q = q.Where(squirrel.Eq{f.column: nil})
+	}
+
+	ctx, cancel := s.context()
+	defer cancel()
+	ret, err := s.exec(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return ret.RowsAffected()
+}
+
+// Describe returns the table name, and the columns of the bound Record, in
+// the order of its fields.
+//
+// The description is a copy, so changing it does not change the binding.
+func (s *DbRecorder) Describe() TableDescription {
+	desc := TableDescription{
+		TableName: s.table,
+		Columns:   make([]ColumnDescription, len(s.fields)),
+	}
+	for i, f := range s.fields {
+		desc.Columns[i] = ColumnDescription{
+			Name:         f.column,
+			FieldName:    f.name,
+			IsPrimaryKey: f.isKey,
+			IsAuto:       f.isAuto,
+		}
+	}
+	return desc
 }
 
 // Columns returns the names of the columns on this table.
@@ -639,20 +3273,10 @@ func (s *DbRecorder) FieldReferences(withKeys bool) []interface{} {
 			continue
 		}
 
+		// For a pointer field, this is a pointer to the pointer, which Scan
+		// sets to nil for NULL, and to a new value otherwise.
 		fv := ar.FieldByName(field.name)
-		var ref reflect.Value
-		if fv.Kind() != reflect.Ptr {
-			// we want the address of field
-			ref = fv.Addr()
-		} else {
-			// we already have an address
-			ref = fv
-			if fv.IsNil() {
-				// allocate a new element of same type
-				fv.Set(reflect.New(fv.Type().Elem()))
-			}
-		}
-		refs = append(refs, ref.Interface())
+		refs = append(refs, fv.Addr().Interface())
 	}
 
 	return refs
@@ -661,8 +3285,11 @@ func (s *DbRecorder) FieldReferences(withKeys bool) []interface{} {
 // colValLists returns 2 lists, the column names and values.
 // If withKeys is false, columns and values of fields designated as primary keys
 // will not be included in those lists. Also, if withAutos is false, the returned
-// lists will not include fields designated as auto-increment.
-func (s *DbRecorder) colValLists(withKeys, withAutos bool) (columns []string, values []interface{}) {
+// lists will not include fields designated as auto-increment. READ_ONLY fields
+// are never included, as the lists are used to write.
+// If omitNil is true, a column represented by pointer will be omitted if this
+// pointer is nil in current record. Otherwise its value is a SQL NULL.
+func (s *DbRecorder) colValLists(withKeys, withAutos, omitNil bool) (columns []string, values []interface{}) {
 	ar := reflect.Indirect(reflect.ValueOf(s.record))
 
 	for _, field := range s.fields {
@@ -672,14 +3299,26 @@ func (s *DbRecorder) colValLists(withKeys, withAutos bool) (columns []string, va
 			continue
 		case !withAutos && field.isAuto:
 			continue
+		case field.isReadOnly:
+			continue
 		}
 
 		// Get the value of the field we are going to store.
 		f := ar.FieldByName(field.name)
 		var v reflect.Value
 		if f.Kind() == reflect.Ptr {
+			if sentinel, ok := s.sentinels[field.column]; ok && f.IsNil() {
+				values = append(values, sentinel)
+				columns = append(columns, field.column)
+				continue
+			}
 			if f.IsNil() {
-				// nothing to store
+				if omitNil {
+					// nothing to store
+					continue
+				}
+				values = append(values, nil)
+				columns = append(columns, field.column)
 				continue
 			}
 			// no indirection: the field is already a reference to its value
@@ -687,6 +3326,12 @@ func (s *DbRecorder) colValLists(withKeys, withAutos bool) (columns []string, va
 		} else {
 			// get the value pointed to by the field
 			v = reflect.Indirect(f)
+			// unless only a pointer to it can produce its SQL value
+			if _, ok := v.Interface().(driver.Valuer); !ok && f.CanAddr() {
+				if _, ok := f.Addr().Interface().(driver.Valuer); ok {
+					v = f.Addr()
+				}
+			}
 		}
 
 		values = append(values, v.Interface())
@@ -696,11 +3341,38 @@ func (s *DbRecorder) colValLists(withKeys, withAutos bool) (columns []string, va
 	return
 }
 
+// clearSentinels sets the pointer fields that hold their column's NULL
+// sentinel to nil.
+func (s *DbRecorder) clearSentinels() {
+	if len(s.sentinels) == 0 {
+		return
+	}
+
+	ar := reflect.Indirect(reflect.ValueOf(s.record))
+	for _, field := range s.fields {
+		sentinel, ok := s.sentinels[field.column]
+		if !ok {
+			continue
+		}
+		f := ar.FieldByName(field.name)
+		if f.Kind() != reflect.Ptr || f.IsNil() {
+			continue
+		}
+		sv := reflect.ValueOf(sentinel)
+		if !sv.IsValid() || !sv.Type().ConvertibleTo(f.Type().Elem()) {
+			continue
+		}
+		if f.Elem().Interface() == sv.Convert(f.Type().Elem()).Interface() {
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+}
+
 // updateFields produces fields to go into SetMap for an update.
 // This will NOT update PRIMARY_KEY fields.
 func (s *DbRecorder) updateFields() map[string]interface{} {
 	update := map[string]interface{}{}
-	cols, vals := s.colValLists(false, true)
+	cols, vals := s.colValLists(false, true, false)
 	for i, col := range cols {
 		update[col] = vals[i]
 	}
@@ -721,22 +3393,77 @@ func (s *DbRecorder) WhereIds() map[string]interface{} {
 	return clause
 }
 
+// namingStrategy names the columns of fields without a stbl tag. If it is
+// nil, those fields are not columns.
+var namingStrategy func(string) string
+
+// SetNamingStrategy sets the function that derives the column of an exported
+// field that has no stbl tag, from the name of the field. If it returns an
+// empty string, the field is not a column. A nil function restores the
+// default, where fields without a tag are not columns.
+//
+// The strategy applies to Records bound after it is set, so set it once,
+// before binding any Record.
+func SetNamingStrategy(fn func(goFieldName string) string) {
+	namingStrategy = fn
+}
+
+// SnakeCase converts a Go field name to snake case, so that FirstName
+// becomes first_name and UserID becomes user_id. It is meant to be passed
+// to SetNamingStrategy.
+func SnakeCase(goFieldName string) string {
+	runes := []rune(goFieldName)
+	out := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// A word starts at an upper case letter, unless it continues
+			// an acronym, like the ID of UserID.
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
 // scanFields extracts the tags from all of the fields on a struct.
 func (s *DbRecorder) scanFields(ar Record) {
-	v := reflect.Indirect(reflect.ValueOf(ar))
-	t := v.Type()
+	s.fields = nil
+	s.key = make([]*field, 0, 2)
+	s.scanStruct(reflect.Indirect(reflect.ValueOf(ar)).Type())
+
+	sort.SliceStable(s.key, func(i, j int) bool {
+		return s.key[i].keyOrder < s.key[j].keyOrder
+	})
+}
+
+// scanStruct adds the tagged fields of a struct type to the fields of the
+// recorder. The fields of embedded structs without a tag are added too, and
+// the other fields without a tag are named by the naming strategy, if any.
+//
+// It panics if two fields have the same name or column, as then the fields
+// could not be told apart.
+func (s *DbRecorder) scanStruct(t reflect.Type) {
 	count := t.NumField()
-	keys := make([]*field, 0, 2)
 
 	for i := 0; i < count; i++ {
 		f := t.Field(i)
-		// Skip fields with no tag.
-		if len(f.Tag) == 0 {
+		sqtag := f.Tag.Get("stbl")
+		if sqtag == "-" {
 			continue
 		}
-		sqtag := f.Tag.Get("stbl")
 		if len(sqtag) == 0 {
-			continue
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				s.scanStruct(f.Type)
+			}
+			if f.Anonymous || f.PkgPath != "" || namingStrategy == nil {
+				continue
+			}
+			if sqtag = namingStrategy(f.Name); sqtag == "" {
+				continue
+			}
 		}
 
 		parts := s.parseTag(f.Name, sqtag)
@@ -748,13 +3475,32 @@ func (s *DbRecorder) scanFields(ar Record) {
 			switch part {
 			case "PRIMARY_KEY", "PRIMARY KEY":
 				field.isKey = true
-				keys = append(keys, field)
+				s.key = append(s.key, field)
 			case "AUTO_INCREMENT", "SERIAL", "AUTO INCREMENT":
 				field.isAuto = true
+			case "SOFT_DELETE":
+				field.isSoftDelete = true
+			case "VERSION":
+				field.isVersion = true
+			case "AUTO_CREATE":
+				field.isAutoCreate = true
+			case "AUTO_UPDATE":
+				field.isAutoUpdate = true
+			case "READ_ONLY":
+				field.isReadOnly = true
+			default:
+				if strings.HasPrefix(part, "KEY_ORDER=") {
+					field.keyOrder, _ = strconv.Atoi(strings.TrimPrefix(part, "KEY_ORDER="))
+				}
+			}
+		}
+		for _, other := range s.fields {
+			if other.name == field.name || other.column == field.column {
+				panic(fmt.Sprintf("Field %s (column %s) of %s collides with field %s (column %s)",
+					field.name, field.column, t, other.name, other.column))
 			}
 		}
 		s.fields = append(s.fields, field)
-		s.key = keys
 	}
 }
 
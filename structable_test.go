@@ -1,12 +1,16 @@
 package structable
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 )
@@ -254,21 +258,1268 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestInsertMap(t *testing.T) {
+	db := new(DBStub)
+	values := map[string]interface{}{"material": "Wood", "number_of_legs": 3}
+
+	id, err := InsertMap(db, "mysql", "test_table", values, nil)
+	if err != nil {
+		t.Errorf("InsertMap error: %s", err)
+	}
+	if id != 1 {
+		t.Errorf("Expected ID 1, got %d", id)
+	}
+	expect := "INSERT INTO test_table (material,number_of_legs) VALUES (?,?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastExecSql)
+	}
+
+	if _, err := InsertMap(db, "postgres", "test_table", values, newStool()); err != nil {
+		t.Errorf("InsertMap error: %s", err)
+	}
+	expect = "INSERT INTO test_table (material,number_of_legs) VALUES ($1,$2) RETURNING id"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQueryRowSql)
+	}
+
+	values["legs"] = 4
+	if _, err := InsertMap(db, "mysql", "test_table", values, newStool()); err == nil {
+		t.Error("Expected an error for a column the prototype lacks")
+	}
+}
+
+func TestInsertOracle(t *testing.T) {
+	stool := newStool()
+	db := new(DBStub)
+
+	r := New(db, "oracle").Bind("test_table", stool)
+	if err := r.Insert(); err != nil {
+		t.Errorf("Failed insert: %s", err)
+	}
+
+	expect := "INSERT INTO test_table (id_two,number_of_legs,material) VALUES (:1,:2,:3) RETURNING id INTO :4"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastExecSql)
+	}
+	last := db.LastExecArgs[len(db.LastExecArgs)-1]
+	if out, ok := last.(sql.Out); !ok || out.Dest != &stool.Id {
+		t.Errorf("Expected the ID field as the RETURNING destination, got %v", last)
+	}
+}
+
+func TestUpdateIf(t *testing.T) {
+	stool := newStool()
+	db := new(DBStub)
+
+	rec := New(db, "mysql").Bind("test_table", stool)
+
+	n, err := rec.UpdateIf(squirrel.Eq{"material": "Wood"})
+	if err != nil {
+		t.Errorf("UpdateIf error: %s", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 affected row, got %d", n)
+	}
+
+	if !strings.HasSuffix(db.LastExecSql, "WHERE id = ? AND id_two = ? AND (material = ?)") {
+		t.Errorf("Unexpected SQL: %s", db.LastExecSql)
+	}
+	if last := db.LastExecArgs[len(db.LastExecArgs)-1]; last != "Wood" {
+		t.Errorf("Expected guard arg last, got %v", last)
+	}
+
+	stool.Material = "Steel"
+	if _, err := rec.UpdateIf(squirrel.Eq{"material": "Wood"}); err != nil {
+		t.Errorf("UpdateIf error: %s", err)
+	}
+	if changed := rec.Changed(); len(changed) != 0 {
+		t.Errorf("Expected UpdateIf to remember the saved values, got %v changed", changed)
+	}
+}
+
+type Membership struct {
+	UserId   int    `stbl:"user_id,PRIMARY_KEY,KEY_ORDER=2"`
+	TenantId int    `stbl:"tenant_id,PRIMARY_KEY,KEY_ORDER=1"`
+	Role     string `stbl:"role"`
+}
+
+func TestKeyOrder(t *testing.T) {
+	r := New(new(DBStub), "mysql").Bind("memberships", &Membership{})
+
+	key := r.(*DbRecorder).Key()
+	if len(key) != 2 || key[0] != "tenant_id" || key[1] != "user_id" {
+		t.Errorf("Expected key (tenant_id, user_id), got %v", key)
+	}
+}
+
+type Post struct {
+	Id        int        `stbl:"id,PRIMARY_KEY"`
+	Title     string     `stbl:"title"`
+	DeletedAt *time.Time `stbl:"deleted_at,SOFT_DELETE"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	db := new(DBStub)
+	post := &Post{Id: 3}
+	r := New(db, "mysql").Bind("posts", post)
+
+	if err := r.Delete(); err != nil {
+		t.Fatalf("Error calling Delete: %s", err)
+	}
+	expect := "UPDATE posts SET deleted_at = ? WHERE id = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if post.DeletedAt == nil {
+		t.Error("Expected Delete to set DeletedAt")
+	} else if post.DeletedAt.Location() != time.UTC {
+		t.Errorf("Expected DeletedAt in UTC, like the AUTO_CREATE fields, got %s", post.DeletedAt.Location())
+	}
+	if at, ok := db.LastExecArgs[0].(time.Time); !ok || at.Location() != time.UTC {
+		t.Errorf("Expected deleted_at to be written in UTC, got %v", db.LastExecArgs[0])
+	}
+
+	r.Load()
+	expect = "SELECT title, deleted_at FROM posts WHERE id = ? AND deleted_at IS NULL"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+	r.Exists()
+	expect = "SELECT COUNT(*) > 0 FROM posts WHERE id = ? AND deleted_at IS NULL"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if _, err := r.DeleteWhere(squirrel.Eq{"title": "Draft"}); err != nil {
+		t.Fatalf("Error calling DeleteWhere: %s", err)
+	}
+	if at, ok := db.LastExecArgs[0].(time.Time); !ok || at.Location() != time.UTC {
+		t.Errorf("Expected DeleteWhere to write deleted_at in UTC, got %v", db.LastExecArgs[0])
+	}
+
+	if err := r.ForceDelete(); err != nil {
+		t.Fatalf("Error calling ForceDelete: %s", err)
+	}
+	expect = "DELETE FROM posts WHERE id = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+}
+
+func TestWhereConditions(t *testing.T) {
+	db := new(DBStub)
+	r := New(db, "mysql").Bind("posts", &Post{})
+
+	cond := squirrel.And{
+		squirrel.Or{squirrel.Eq{"title": "a"}, squirrel.Eq{"title": "b"}},
+		squirrel.Eq{"id": 3},
+	}
+	r.LoadWhere(cond)
+	expect := "SELECT id, title, deleted_at FROM posts WHERE ((title = ? OR title = ?) AND id = ?) AND deleted_at IS NULL"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+	if len(db.LastQueryRowArgs) != 3 {
+		t.Errorf("Expected 3 args, got %v", db.LastQueryRowArgs)
+	}
+
+	r.ExistsWhere("title = ? OR title = ?", "a", "b")
+	expect = "SELECT 1 FROM posts WHERE (title = ? OR title = ?) AND deleted_at IS NULL LIMIT 1"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+	if len(db.LastQueryRowArgs) != 2 {
+		t.Errorf("Expected 2 args, got %v", db.LastQueryRowArgs)
+	}
+
+	r.Count(squirrel.Or{squirrel.Eq{"id": 1}, squirrel.Expr("title LIKE ?", "x%")})
+	expect = "SELECT COUNT(*) FROM posts WHERE (id = ? OR title LIKE ?) AND deleted_at IS NULL"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	// Without a condition of structable's own, strings are left alone.
+	s := New(db, "mysql").Bind("test_table", newStool())
+	s.ExistsWhere("id = ? OR id = ?", 1, 2)
+	expect = "SELECT 1 FROM test_table WHERE id = ? OR id = ? LIMIT 1"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+}
+
+type Hooked struct {
+	Id     int    `stbl:"id,PRIMARY_KEY"`
+	Name   string `stbl:"name"`
+	loaded bool
+}
+
+func (h *Hooked) BeforeInsert() error {
+	h.Name = strings.TrimSpace(h.Name)
+	return nil
+}
+
+func (h *Hooked) BeforeDelete() error {
+	return errors.New("Cannot delete")
+}
+
+func (h *Hooked) AfterLoad() {
+	h.loaded = true
+}
+
+func TestHooks(t *testing.T) {
+	db := new(DBStub)
+	h := &Hooked{Id: 1, Name: " padded "}
+	r := New(db, "mysql").Bind("hooked", h)
+
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Error calling Insert: %s", err)
+	}
+	if h.Name != "padded" {
+		t.Errorf("Expected BeforeInsert to trim the name, got %q", h.Name)
+	}
+
+	db.LastExecSql = ""
+	if err := r.Delete(); err == nil || err.Error() != "Cannot delete" {
+		t.Errorf("Expected BeforeDelete to abort, got %v", err)
+	}
+	if db.LastExecSql != "" {
+		t.Errorf("Expected no statement, got %q", db.LastExecSql)
+	}
+
+	if err := r.Load(); err != nil {
+		t.Fatalf("Error calling Load: %s", err)
+	}
+	if !h.loaded {
+		t.Error("Expected AfterLoad to run")
+	}
+}
+
+type Node struct {
+	Id       int    `stbl:"id,PRIMARY_KEY"`
+	ParentId *int64 `stbl:"parent_id"`
+}
+
+func TestLoadMany(t *testing.T) {
+	db := new(DBStub)
+	r := New(db, "mysql").Bind("nodes", &Node{Id: 7})
+	child := New(db, "mysql").Bind("nodes", &Node{})
+
+	if _, err := r.LoadMany(child, "parent_id"); err != nil {
+		t.Errorf("LoadMany error: %s", err)
+	}
+	expect := "SELECT id, parent_id FROM nodes WHERE parent_id = ?"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQuerySql)
+	}
+	if len(db.LastQueryArgs) != 1 || db.LastQueryArgs[0] != 7 {
+		t.Errorf("Expected the parent key as the only arg, got %v", db.LastQueryArgs)
+	}
+
+	if _, err := r.LoadMany(child, "owner_id"); err == nil {
+		t.Error("Expected an error for a column the child lacks")
+	}
+	if _, err := New(db, "mysql").Bind("test_table", newStool()).LoadMany(child, "parent_id"); err == nil {
+		t.Error("Expected an error for a composite primary key")
+	}
+}
+
+func TestLoadRelated(t *testing.T) {
+	db := new(DBStub)
+	parentId := int64(5)
+	r := New(db, "mysql").Bind("nodes", &Node{Id: 7, ParentId: &parentId})
+	parent := &Node{}
+	target := New(db, "mysql").Bind("nodes", parent)
+
+	if err := r.LoadRelated("parent_id", target); err != nil {
+		t.Errorf("LoadRelated error: %s", err)
+	}
+	if parent.Id != 5 {
+		t.Errorf("Expected the target key to be 5, got %d", parent.Id)
+	}
+	expect := "SELECT parent_id FROM nodes WHERE id = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQueryRowSql)
+	}
+
+	if err := r.LoadRelated("owner_id", target); err == nil {
+		t.Error("Expected an error for a column the record lacks")
+	}
+	if err := r.LoadRelated("parent_id", New(db, "mysql").Bind("test_table", newStool())); err == nil {
+		t.Error("Expected an error for a composite primary key")
+	}
+	if err := r.LoadRelated("parent_id", New(db, "mysql").Bind("slugs", &struct {
+		Slug string `stbl:"slug,PRIMARY_KEY"`
+	}{})); err == nil {
+		t.Error("Expected an error for a key of another type")
+	}
+
+	orphan := New(db, "mysql").Bind("nodes", &Node{Id: 8})
+	if err := orphan.LoadRelated("parent_id", target); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for a NULL foreign key, got %v", err)
+	}
+}
+
+func TestWithPlaceholder(t *testing.T) {
+	db := new(DBStub)
+	if err := New(db, "postgres").Bind("nodes", &Node{Id: 7}).Load(); err != nil {
+		t.Errorf("Load error: %s", err)
+	}
+	expect := "SELECT parent_id FROM nodes WHERE id = $1"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQueryRowSql)
+	}
+
+	// The format outlives a new database from WithLogger.
+	r := New(db, "postgres", WithPlaceholder(squirrel.Question)).WithLogger(func(string, []interface{}, time.Duration, error) {})
+	r.Bind("nodes", &Node{Id: 7})
+	if err := r.Load(); err != nil {
+		t.Errorf("Load error: %s", err)
+	}
+	expect = "SELECT parent_id FROM nodes WHERE id = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQueryRowSql)
+	}
+
+	if _, err := r.LoadMany(New(db, "postgres").Bind("nodes", &Node{}), "parent_id"); err != nil {
+		t.Errorf("LoadMany error: %s", err)
+	}
+	expect = "SELECT id, parent_id FROM nodes WHERE parent_id = ?"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQuerySql)
+	}
+}
+
+func TestNullSentinel(t *testing.T) {
+	node := &Node{Id: 1}
+	db := new(DBStub)
+
+	r := New(db, "mysql").WithNullSentinel("parent_id", -1)
+	r.Bind("nodes", node)
+
+	if err := r.Insert(); err != nil {
+		t.Errorf("Failed insert: %s", err)
+	}
+	expect := "INSERT INTO nodes (id,parent_id) VALUES (?,?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastExecSql)
+	}
+	if got := db.LastExecArgs[1]; got != -1 {
+		t.Errorf("Expected sentinel -1 for nil field, got %v", got)
+	}
+
+	// As if -1 had been loaded from the database.
+	loaded := int64(-1)
+	node.ParentId = &loaded
+	r.clearSentinels()
+	if node.ParentId != nil {
+		t.Errorf("Expected sentinel to load as nil, got %d", *node.ParentId)
+	}
+
+	other := int64(7)
+	node.ParentId = &other
+	r.clearSentinels()
+	if node.ParentId == nil || *node.ParentId != 7 {
+		t.Error("Expected non-sentinel value to be kept")
+	}
+}
+
 func TestDelete(t *testing.T) {
 	stool := newStool()
 	db := &DBStub{}
 	r := New(db, "mysql").Bind("test_table", stool)
 
-	if err := r.Delete(); err != nil {
-		t.Errorf("Failed to delete: %s", err)
+	if err := r.Delete(); err != nil {
+		t.Errorf("Failed to delete: %s", err)
+	}
+
+	expect := "DELETE FROM test_table WHERE .* AND .*"
+	if ok, _ := regexp.MatchString(expect, db.LastExecSql); !ok {
+		t.Errorf("Unexpect query: %s", db.LastExecSql)
+	}
+	if got := db.LastExecArgs[0].(int); got != 1 {
+		t.Errorf("Expected 1, got %d", got)
+	}
+}
+
+func TestBulkUpsert(t *testing.T) {
+	db := &DBStub{}
+	recs := []interface{}{
+		New(db, "postgres").Bind("test_table", newStool()),
+		New(db, "postgres").Bind("test_table", newStool()),
+	}
+
+	n, err := BulkUpsert(db, "postgres", recs, []string{"id_two"})
+	if err != nil {
+		t.Fatalf("Failed upsert: %s", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 affected row, got %d", n)
+	}
+
+	expect := "INSERT INTO test_table (id_two,number_of_legs,material,color) VALUES ($1,$2,$3,$4),($5,$6,$7,$8) " +
+		"ON CONFLICT (id_two) DO UPDATE SET number_of_legs = EXCLUDED.number_of_legs, material = EXCLUDED.material, color = EXCLUDED.color"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if len(db.LastExecArgs) != 8 || db.LastExecArgs[3] != nil {
+		t.Errorf("Expected 8 args with a NULL color, got %v", db.LastExecArgs)
+	}
+
+	if _, err := BulkUpsert(db, "postgres", recs, []string{"nope"}); err == nil {
+		t.Error("Expected unknown conflict column to fail")
+	}
+	if _, err := BulkUpsert(db, "oracle", recs, []string{"id_two"}); err == nil {
+		t.Error("Expected unsupported flavor to fail")
+	}
+}
+
+func TestInsertMany(t *testing.T) {
+	db := &DBStub{}
+	recs := []interface{}{
+		New(db, "mysql").Bind("test_table", newStool()),
+		New(db, "mysql").Bind("test_table", newStool()),
+	}
+
+	if _, err := InsertMany(db, "mysql", recs); err != nil {
+		t.Fatalf("Failed InsertMany: %s", err)
+	}
+	expect := "INSERT INTO test_table (id_two,number_of_legs,material,color) VALUES (?,?,?,?),(?,?,?,?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if len(db.LastExecArgs) != 8 {
+		t.Errorf("Expected 8 args, got %v", db.LastExecArgs)
+	}
+
+	recs = append(recs, New(db, "mysql").Bind("other_table", newStool()))
+	if _, err := InsertMany(db, "mysql", recs); err == nil {
+		t.Error("Expected records of different tables to fail")
+	}
+
+	// A row that does not fit in one statement cannot be inserted.
+	r := New(db, "sqlite3")
+	r.Bind("test_table", newStool())
+	cols := make([]string, 1000)
+	if _, err := r.insertRows(cols, [][]interface{}{make([]interface{}, 1000)}, ""); err == nil {
+		t.Error("Expected too many columns to fail")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "postgres").Bind("test_table", newStool())
+
+	if err := r.Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	expect := "INSERT INTO test_table (id,id_two,number_of_legs,material,color) VALUES ($1,$2,$3,$4,$5) " +
+		"ON CONFLICT (id,id_two) DO UPDATE SET number_of_legs = EXCLUDED.number_of_legs, material = EXCLUDED.material, color = EXCLUDED.color"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	r = New(db, "mysql").Bind("test_table", newStool())
+	if err := r.Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	expect = "INSERT INTO test_table (id,id_two,number_of_legs,material,color) VALUES (?,?,?,?,?) " +
+		"ON DUPLICATE KEY UPDATE number_of_legs = VALUES(number_of_legs), material = VALUES(material), color = VALUES(color)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	r = New(db, "oracle").Bind("test_table", newStool())
+	if err := r.Upsert(); err == nil {
+		t.Error("Expected unsupported flavor to fail")
+	}
+
+	// An existing row keeps its AUTO_CREATE time.
+	stamped := &Stamped{Id: 2}
+	r = New(db, "postgres").Bind("stamped", stamped)
+	if err := r.Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	expect = "INSERT INTO stamped (id,created_at,updated_at) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET updated_at = EXCLUDED.updated_at"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if stamped.CreatedAt.IsZero() || stamped.UpdatedAt == nil {
+		t.Errorf("Expected Upsert to set the times, got %+v", stamped)
+	}
+	if c := r.Changed(); len(c) != 0 {
+		t.Errorf("Expected no changes after Upsert, got %v", c)
+	}
+
+	// A new record has no key yet.
+	r = New(db, "postgres").Bind("stamped", &Stamped{})
+	if err := r.Upsert(); err == nil {
+		t.Error("Expected a zero SERIAL key to fail")
+	}
+}
+
+func TestUpsertVersion(t *testing.T) {
+	db := &DBStub{}
+	v := &Versioned{Id: 1, Name: "a", Version: 3}
+
+	if err := New(db, "postgres").Bind("versioned", v).Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	expect := "INSERT INTO versioned (id,name,version) VALUES ($1,$2,$3) " +
+		"ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, version = versioned.version + 1 " +
+		"WHERE versioned.version = EXCLUDED.version RETURNING version"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if err := New(db, "mysql").Bind("versioned", v).Upsert(); err != nil {
+		t.Fatalf("Failed Upsert: %s", err)
+	}
+	expect = "INSERT INTO versioned (id,name,version) VALUES (?,?,?) ON DUPLICATE KEY UPDATE " +
+		"name = IF(version = VALUES(version), VALUES(name), name), " +
+		"version = IF(version = VALUES(version), version + 1, version)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	// The stub reports one affected row, which is an insert.
+	if v.Version != 3 {
+		t.Errorf("Expected version 3 after an insert, got %d", v.Version)
+	}
+}
+
+func TestBulkStamps(t *testing.T) {
+	db := &DBStub{}
+	a, b := &Stamped{}, &Stamped{}
+	recs := []interface{}{New(db, "postgres").Bind("stamped", a), New(db, "postgres").Bind("stamped", b)}
+
+	if _, err := InsertMany(db, "postgres", recs); err != nil {
+		t.Fatalf("Failed InsertMany: %s", err)
+	}
+	if a.CreatedAt.IsZero() || b.UpdatedAt == nil {
+		t.Errorf("Expected InsertMany to set the times, got %+v and %+v", a, b)
+	}
+	if db.LastExecArgs[0] != a.CreatedAt {
+		t.Errorf("Expected the time to be written, got %v", db.LastExecArgs)
+	}
+
+	if _, err := BulkUpsert(db, "postgres", recs, []string{"id"}); err != nil {
+		t.Fatalf("Failed BulkUpsert: %s", err)
+	}
+	expect := "INSERT INTO stamped (created_at,updated_at) VALUES ($1,$2),($3,$4) ON CONFLICT (id) DO UPDATE SET updated_at = EXCLUDED.updated_at"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	db := &DBStub{}
+	stool := newStool()
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	if err := r.Load(); err != nil {
+		t.Fatalf("Error calling Load: %s", err)
+	}
+	if c := r.Changed(); len(c) != 0 {
+		t.Errorf("Expected no changes after Load, got %v", c)
+	}
+	stool.Legs = 4
+	if c := r.Changed(); len(c) != 1 || c[0] != "number_of_legs" {
+		t.Errorf("Expected number_of_legs to change, got %v", c)
+	}
+
+	if err := r.UpdateColumns(r.Changed()...); err != nil {
+		t.Fatalf("Error calling UpdateColumns: %s", err)
+	}
+	expect := "UPDATE test_table SET number_of_legs = ? WHERE id = ? AND id_two = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if c := r.Changed(); len(c) != 0 {
+		t.Errorf("Expected no changes after UpdateColumns, got %v", c)
+	}
+
+	if err := r.UpdateColumns("nope"); err == nil {
+		t.Error("Expected an unknown column to fail")
+	}
+	if err := r.UpdateColumns("id"); err == nil {
+		t.Error("Expected a key column to fail")
+	}
+}
+
+type Versioned struct {
+	Id      int    `stbl:"id,PRIMARY_KEY"`
+	Name    string `stbl:"name"`
+	Version int    `stbl:"version,VERSION"`
+}
+
+func TestUpdateVersion(t *testing.T) {
+	db := &DBStub{}
+	v := &Versioned{Id: 1, Name: "a", Version: 3}
+	r := New(db, "mysql").Bind("versioned", v)
+
+	if err := r.Update(); err != nil {
+		t.Fatalf("Error calling Update: %s", err)
+	}
+	expect := "UPDATE versioned SET name = ?, version = version + 1 WHERE id = ? AND version = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if len(db.LastExecArgs) != 3 || db.LastExecArgs[2] != 3 {
+		t.Errorf("Expected the loaded version in the arguments, got %v", db.LastExecArgs)
+	}
+	if v.Version != 4 {
+		t.Errorf("Expected version 4 after Update, got %d", v.Version)
+	}
+}
+
+func TestDbRecorderList(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	if _, err := r.List(squirrel.Eq{"material": "Stainless Steel"}, OrderBy("number_of_legs DESC"), Limit(10), Offset(20)); err != nil {
+		t.Fatalf("Error calling List: %s", err)
+	}
+	expect := "SELECT id, id_two, number_of_legs, material, color FROM test_table WHERE material = ? ORDER BY number_of_legs DESC LIMIT 10 OFFSET 20"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQuerySql)
+	}
+
+	if _, err := r.List(nil); err != nil {
+		t.Fatalf("Error calling List: %s", err)
+	}
+	expect = "SELECT id, id_two, number_of_legs, material, color FROM test_table"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQuerySql)
+	}
+
+	if _, err := r.List(squirrel.Eq{"id": []int{1, 2, 3}}); err != nil {
+		t.Fatalf("Error calling List: %s", err)
+	}
+	expect = "SELECT id, id_two, number_of_legs, material, color FROM test_table WHERE id IN (?,?,?)"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQuerySql)
+	}
+	if len(db.LastQueryArgs) != 3 {
+		t.Errorf("Expected 3 arguments, got %v", db.LastQueryArgs)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	var stools []*Stool
+	if err := r.LoadAll(&stools, "number_of_legs > 2", Limit(5)); err != nil {
+		t.Errorf("LoadAll error: %s", err)
+	}
+	expect := "SELECT id, id_two, number_of_legs, material, color FROM test_table WHERE number_of_legs > 2 LIMIT 5"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQuerySql)
+	}
+
+	var nodes []Node
+	if err := r.LoadAll(&nodes, nil); err == nil {
+		t.Error("Expected an error for a slice of another type")
+	}
+	if err := r.LoadAll(stools, nil); err == nil {
+		t.Error("Expected an error for a slice that is not a pointer")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	if _, _, err := r.Paginate(squirrel.Eq{"material": "Stainless Steel"}, 3, 25, "id"); err != nil {
+		t.Fatalf("Error calling Paginate: %s", err)
+	}
+	expect := "SELECT id, id_two, number_of_legs, material, color FROM test_table WHERE material = ? ORDER BY id LIMIT 25 OFFSET 50"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQuerySql)
+	}
+	expect = "SELECT COUNT(*) FROM test_table WHERE material = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if _, _, err := r.Paginate(nil, 0, 25, ""); err == nil {
+		t.Error("Expected page 0 to fail")
+	}
+	if _, _, err := r.Paginate(nil, 1, -1, ""); err == nil {
+		t.Error("Expected a negative page size to fail")
+	}
+}
+
+func TestToMap(t *testing.T) {
+	stool := newStool()
+	r := New(&DBStub{}, "mysql").Bind("test_table", stool)
+
+	m, err := ToMap(r, nil)
+	if err != nil {
+		t.Fatalf("Error calling ToMap: %s", err)
+	}
+	if len(m) != 5 || m["number_of_legs"] != 3 || m["id_two"] != 2 {
+		t.Errorf("Unexpected map %v", m)
+	}
+	if _, ok := m["Ignored"]; ok {
+		t.Error("Expected untagged fields to be left out")
+	}
+
+	other := Stool{Material: "Wood"}
+	if m, err = ToMap(r, other); err != nil || m["material"] != "Wood" {
+		t.Errorf("Expected the other stool, got %v (%v)", m, err)
+	}
+	if _, err := ToMap(r, &ActRec{}); err == nil {
+		t.Error("Expected a Record of another type to fail")
+	}
+}
+
+type Base struct {
+	Id        int       `stbl:"id,PRIMARY_KEY,SERIAL"`
+	CreatedAt time.Time `stbl:"created_at"`
+}
+
+type Widget struct {
+	Base
+	Name string `stbl:"name"`
+}
+
+func TestEmbeddedStruct(t *testing.T) {
+	db := &DBStub{}
+	w := &Widget{Name: "sprocket"}
+	w.Id = 7
+	r := New(db, "mysql").Bind("widgets", w)
+
+	if k := r.WhereIds(); len(k) != 1 || k["id"] != 7 {
+		t.Errorf("Expected the key of the embedded struct, got %v", k)
+	}
+	if err := r.Update(); err != nil {
+		t.Fatalf("Error calling Update: %s", err)
+	}
+	expect := "UPDATE widgets SET created_at = ?, name = ? WHERE id = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if db.LastExecArgs[2] != 7 {
+		t.Errorf("Expected id 7, got %v", db.LastExecArgs[2])
+	}
+
+	type Clash struct {
+		Base
+		Other int `stbl:"id"`
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a column declared twice to panic")
+		}
+	}()
+	New(db, "mysql").Bind("clashes", &Clash{})
+}
+
+type Stamped struct {
+	Id        int        `stbl:"id,PRIMARY_KEY,SERIAL"`
+	CreatedAt time.Time  `stbl:"created_at,AUTO_CREATE"`
+	UpdatedAt *time.Time `stbl:"updated_at,AUTO_UPDATE"`
+}
+
+type Conventional struct {
+	Id         int `stbl:"id,PRIMARY_KEY,SERIAL"`
+	FirstName  string
+	HTTPStatus int
+	Note       string `stbl:"remark"`
+	Skipped    string `stbl:"-"`
+	hidden     string
+}
+
+func TestNamingStrategy(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("people", &Conventional{})
+	expect := []string{"id", "remark"}
+	if cols := r.Columns(true); !reflect.DeepEqual(cols, expect) {
+		t.Errorf("Expected untagged fields to be left out by default, got %v", cols)
+	}
+
+	SetNamingStrategy(SnakeCase)
+	defer SetNamingStrategy(nil)
+	r = New(db, "mysql").Bind("people", &Conventional{FirstName: "Ada"})
+	expect = []string{"id", "first_name", "http_status", "remark"}
+	if cols := r.Columns(true); !reflect.DeepEqual(cols, expect) {
+		t.Errorf("Expected %v, got %v", expect, cols)
+	}
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Insert error: %s", err)
+	}
+	expectSql := "INSERT INTO people (first_name,http_status,remark) VALUES (?,?,?)"
+	if db.LastExecSql != expectSql {
+		t.Errorf("Expected '%s', got '%s'", expectSql, db.LastExecSql)
+	}
+
+	// A generated struct embeds its Recorder, which is never a column.
+	w := &struct {
+		Recorder
+		Name string
+	}{}
+	w.Recorder = New(db, "mysql").Bind("widgets", w)
+	if cols := w.Columns(true); !reflect.DeepEqual(cols, []string{"name"}) {
+		t.Errorf("Expected only the name column, got %v", cols)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("posts", &Post{})
+
+	if _, err := r.UpdateWhere(map[string]interface{}{"title": "x"}, squirrel.Eq{"id": []int{1, 2}}); err != nil {
+		t.Fatalf("Error calling UpdateWhere: %s", err)
+	}
+	expect := "UPDATE posts SET title = ? WHERE id IN (?,?) AND deleted_at IS NULL"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	if _, err := r.UpdateWhere(map[string]interface{}{"title": "x"}, nil); err == nil {
+		t.Error("Expected an empty clause to fail")
+	}
+	if _, err := r.UpdateWhere(map[string]interface{}{"id": 4}, "id = 3"); err == nil {
+		t.Error("Expected a primary key to fail")
+	}
+	if _, err := r.UpdateWhere(map[string]interface{}{"nope": 4}, "id = 3"); err == nil {
+		t.Error("Expected an unknown column to fail")
+	}
+
+	r = New(db, "mysql").Bind("stamped", &Stamped{})
+	if _, err := r.UpdateWhere(map[string]interface{}{"created_at": time.Now()}, "id > 3"); err != nil {
+		t.Fatalf("Error calling UpdateWhere: %s", err)
+	}
+	expect = "UPDATE stamped SET created_at = ?, updated_at = ? WHERE id > 3"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+}
+
+type Computed struct {
+	Id    int    `stbl:"id,PRIMARY_KEY,SERIAL"`
+	Name  string `stbl:"name"`
+	Total int    `stbl:"total,READ_ONLY"`
+	Note  string `stbl:"-"`
+	Base  `stbl:"-"`
+}
+
+func TestReadOnly(t *testing.T) {
+	db := &DBStub{}
+	c := &Computed{Id: 1, Name: "a", Total: 3}
+	r := New(db, "mysql").Bind("computed", c)
+
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Error calling Insert: %s", err)
+	}
+	expect := "INSERT INTO computed (name) VALUES (?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	if err := r.Update(); err != nil {
+		t.Fatalf("Error calling Update: %s", err)
+	}
+	expect = "UPDATE computed SET name = ? WHERE id = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if err := r.UpdateColumns("total"); err == nil {
+		t.Error("Expected a READ_ONLY column to fail")
+	}
+
+	r.Load()
+	expect = "SELECT name, total FROM computed WHERE id = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+}
+
+func TestInsertColumns(t *testing.T) {
+	db := &DBStub{}
+	stool := newStool()
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	if err := r.InsertColumns("material", "color"); err != nil {
+		t.Fatalf("Error calling InsertColumns: %s", err)
+	}
+	expect := "INSERT INTO test_table (material,color) VALUES (?,?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+	if len(db.LastExecArgs) != 2 || db.LastExecArgs[1] != nil {
+		t.Errorf("Expected a nil color, got %v", db.LastExecArgs)
+	}
+
+	if err := r.InsertColumns("id"); err == nil {
+		t.Error("Expected an AUTO_INCREMENT column to fail")
+	}
+	if err := r.InsertColumns("nope"); err == nil {
+		t.Error("Expected an unknown column to fail")
+	}
+	if err := r.InsertColumns(); err == nil {
+		t.Error("Expected no columns to fail")
+	}
+
+	// Insert is not limited by an earlier InsertColumns.
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Error calling Insert: %s", err)
+	}
+	expect = "INSERT INTO test_table (id_two,number_of_legs,material) VALUES (?,?,?)"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	s := &Stamped{}
+	r = New(db, "postgres").Bind("stamped", s)
+	if err := r.InsertColumns("updated_at"); err != nil {
+		t.Fatalf("Error calling InsertColumns: %s", err)
+	}
+	expect = "INSERT INTO stamped (created_at,updated_at) VALUES ($1,$2) RETURNING id,created_at,updated_at"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+}
+
+func TestAutoTimestamps(t *testing.T) {
+	db := &DBStub{}
+	s := &Stamped{}
+	r := New(db, "mysql").Bind("stamped", s)
+
+	if err := r.Insert(); err != nil {
+		t.Fatalf("Error calling Insert: %s", err)
+	}
+	if s.CreatedAt.IsZero() || s.UpdatedAt == nil || !s.UpdatedAt.Equal(s.CreatedAt) {
+		t.Errorf("Expected Insert to set both times, got %v and %v", s.CreatedAt, s.UpdatedAt)
+	}
+	if s.CreatedAt.Location() != time.UTC {
+		t.Errorf("Expected UTC, got %s", s.CreatedAt.Location())
+	}
+
+	created := s.CreatedAt
+	s.UpdatedAt = nil
+	if err := r.Update(); err != nil {
+		t.Fatalf("Error calling Update: %s", err)
+	}
+	if s.CreatedAt != created || s.UpdatedAt == nil {
+		t.Errorf("Expected Update to set updated_at only, got %v and %v", s.CreatedAt, s.UpdatedAt)
+	}
+
+	s.UpdatedAt = nil
+	if err := r.UpdateColumns("created_at"); err != nil {
+		t.Fatalf("Error calling UpdateColumns: %s", err)
+	}
+	expect := "UPDATE stamped SET created_at = ?, updated_at = ? WHERE id = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	then := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := &Stamped{CreatedAt: then}
+	if err := New(db, "mysql").RespectExisting().Bind("stamped", old).Insert(); err != nil {
+		t.Fatalf("Error calling Insert: %s", err)
+	}
+	if old.CreatedAt != then || old.UpdatedAt == nil {
+		t.Errorf("Expected created_at to be kept, got %v and %v", old.CreatedAt, old.UpdatedAt)
+	}
+}
+
+// Secret only produces its SQL value through a pointer.
+type Secret string
+
+func (s *Secret) Value() (driver.Value, error) {
+	return "enc:" + string(*s), nil
+}
+
+type Vault struct {
+	Id     int    `stbl:"id,PRIMARY_KEY"`
+	Secret Secret `stbl:"secret"`
+}
+
+func TestPointerValuer(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("vaults", &Vault{Id: 1, Secret: "x"})
+
+	if err := r.Update(); err != nil {
+		t.Fatalf("Error calling Update: %s", err)
+	}
+	v, ok := db.LastExecArgs[0].(driver.Valuer)
+	if !ok {
+		t.Fatalf("Expected a driver.Valuer, got %T", db.LastExecArgs[0])
+	}
+	if val, _ := v.Value(); val != "enc:x" {
+		t.Errorf("Expected enc:x, got %v", val)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	db := &DBStub{}
+	s := &Stamped{}
+	r := New(db, "postgres").Bind("stamped", s)
+
+	if err := r.InsertReturning(); err != nil {
+		t.Fatalf("Error calling InsertReturning: %s", err)
+	}
+	expect := "INSERT INTO stamped (created_at,updated_at) VALUES ($1,$2) RETURNING id"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if err := r.InsertReturning("id", "created_at"); err != nil {
+		t.Fatalf("Error calling InsertReturning: %s", err)
+	}
+	expect = "INSERT INTO stamped (created_at,updated_at) VALUES ($1,$2) RETURNING id,created_at"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if err := r.InsertReturning("nope"); err == nil {
+		t.Error("Expected an unknown column to fail")
+	}
+
+	r = New(db, "mysql").Bind("stamped", s)
+	if err := r.InsertReturning("created_at"); err == nil {
+		t.Error("Expected MySQL to fail to return created_at")
+	}
+	if err := r.InsertReturning("id"); err != nil {
+		t.Errorf("Expected MySQL to return the AUTO_INCREMENT id, got %s", err)
+	}
+	if s.Id != 1 {
+		t.Errorf("Expected id 1, got %d", s.Id)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	n, err := r.DeleteWhere(squirrel.Eq{"material": "Wood"})
+	if err != nil {
+		t.Fatalf("Error calling DeleteWhere: %s", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 deleted row, got %d", n)
+	}
+	expect := "DELETE FROM test_table WHERE material = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+
+	for _, pred := range []interface{}{nil, "", squirrel.Eq{}, squirrel.And{}} {
+		if _, err := r.DeleteWhere(pred); err == nil {
+			t.Errorf("Expected an empty condition %#v to fail", pred)
+		}
+	}
+
+	if _, err := r.DeleteAll(); err != nil {
+		t.Fatalf("Error calling DeleteAll: %s", err)
+	}
+	if db.LastExecSql != "DELETE FROM test_table" {
+		t.Errorf("Expected DELETE FROM test_table, got %q", db.LastExecSql)
+	}
+
+	if _, err := New(db, "mysql").Bind("posts", &Post{}).DeleteWhere(squirrel.Lt{"id": 10}); err != nil {
+		t.Fatalf("Error calling DeleteWhere: %s", err)
+	}
+	expect = "UPDATE posts SET deleted_at = ? WHERE deleted_at IS NULL AND id < ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastExecSql)
+	}
+}
+
+func TestSave(t *testing.T) {
+	db := &DBStub{}
+	s := &Stamped{}
+	r := New(db, "mysql").Bind("stamped", s)
+
+	if err := r.Save(); err != nil {
+		t.Fatalf("Error calling Save: %s", err)
+	}
+	if !strings.HasPrefix(db.LastExecSql, "INSERT INTO stamped") {
+		t.Errorf("Expected a new record to be inserted, got %q", db.LastExecSql)
+	}
+	if err := r.Save(); err != nil {
+		t.Fatalf("Error calling Save: %s", err)
+	}
+	if !strings.HasPrefix(db.LastExecSql, "UPDATE stamped") {
+		t.Errorf("Expected a record with an id to be updated, got %q", db.LastExecSql)
+	}
+
+	db = &DBStub{}
+	if err := New(db, "mysql").Bind("posts", &Post{Id: 3}).Save(); err != nil {
+		t.Fatalf("Error calling Save: %s", err)
+	}
+	if !strings.HasPrefix(db.LastQueryRowSql, "SELECT COUNT(*) > 0 FROM posts") {
+		t.Errorf("Expected Save to check whether the post exists, got %q", db.LastQueryRowSql)
+	}
+	if !strings.HasPrefix(db.LastExecSql, "INSERT INTO posts") {
+		t.Errorf("Expected a missing post to be inserted, got %q", db.LastExecSql)
+	}
+
+	if err := New(db, "mysql").Bind("vaults", &struct {
+		Name string `stbl:"name"`
+	}{}).Save(); err == nil {
+		t.Error("Expected a record without a key to fail")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	r := New(&DBStub{}, "mysql").Bind("test_table", newStool())
+
+	desc := r.Describe()
+	if desc.TableName != "test_table" {
+		t.Errorf("Expected test_table, got %s", desc.TableName)
+	}
+	if len(desc.Columns) != 5 {
+		t.Fatalf("Expected 5 columns, got %d", len(desc.Columns))
+	}
+	expect := ColumnDescription{Name: "id", FieldName: "Id", IsPrimaryKey: true, IsAuto: true}
+	if desc.Columns[0] != expect {
+		t.Errorf("Expected %v, got %v", expect, desc.Columns[0])
+	}
+	expect = ColumnDescription{Name: "number_of_legs", FieldName: "Legs"}
+	if desc.Columns[2] != expect {
+		t.Errorf("Expected %v, got %v", expect, desc.Columns[2])
+	}
+}
+
+func TestUpdateWith(t *testing.T) {
+	db, other := &DBStub{}, &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	if err := r.UpdateWith(other); err != nil {
+		t.Fatalf("Error calling UpdateWith: %s", err)
+	}
+	if !strings.HasPrefix(other.LastExecSql, "UPDATE test_table") {
+		t.Errorf("Expected the update to run with the runner, got %q", other.LastExecSql)
+	}
+	if db.LastExecSql != "" {
+		t.Errorf("Expected the database of the recorder to be left alone, got %q", db.LastExecSql)
+	}
+
+	if err := r.Delete(); err != nil {
+		t.Fatalf("Error calling Delete: %s", err)
+	}
+	if !strings.HasPrefix(db.LastExecSql, "DELETE FROM test_table") {
+		t.Errorf("Expected the binding to be intact, got %q", db.LastExecSql)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	db := &DBStub{}
+	var logged []string
+	logger := func(sql string, args []interface{}, dur time.Duration, err error) {
+		logged = append(logged, fmt.Sprintf("%s %v", sql, args))
+	}
+	r := New(db, "mysql").WithLogger(logger).WithLogger(logger).Bind("test_table", newStool())
+
+	if err := r.Load(); err != nil {
+		t.Fatalf("Error calling Load: %s", err)
+	}
+	if err := r.Delete(); err != nil {
+		t.Fatalf("Error calling Delete: %s", err)
+	}
+	expect := []string{
+		"SELECT number_of_legs, material, color FROM test_table WHERE id = ? AND id_two = ? [1 2]",
+		"DELETE FROM test_table WHERE id = ? AND id_two = ? [1 2]",
+	}
+	if !reflect.DeepEqual(logged, expect) {
+		t.Errorf("Expected %q, got %q", expect, logged)
+	}
+
+	New(db, "mysql").WithLogger(logger).WithLogger(nil).Bind("test_table", newStool()).Delete()
+	if len(logged) != 2 {
+		t.Errorf("Expected a nil logger to stop logging, got %q", logged[2:])
+	}
+}
+
+func TestWithLoggerDuration(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	var dur time.Duration
+	logger := func(sql string, args []interface{}, d time.Duration, err error) {
+		dur = d
+	}
+	db := &SlowDBStub{DBStub: &DBStub{}, delay: delay}
+	r := New(db, "mysql").WithLogger(logger).Bind("test_table", newStool())
+
+	// The time spent in QueryRow counts, not just the Scan after it.
+	if err := r.Load(); err != nil {
+		t.Fatalf("Error calling Load: %s", err)
+	}
+	if dur < delay {
+		t.Errorf("Expected QueryRow to take at least %s, got %s", delay, dur)
+	}
+
+	dur = 0
+	if err := r.LoadContext(context.Background()); err != nil {
+		t.Fatalf("Error calling LoadContext: %s", err)
+	}
+	if dur < delay {
+		t.Errorf("Expected QueryRowContext to take at least %s, got %s", delay, dur)
+	}
+}
+
+func TestCount(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	if _, err := r.Count(squirrel.Eq{"material": "Stainless Steel"}); err != nil {
+		t.Fatalf("Error calling Count: %s", err)
+	}
+	expect := "SELECT COUNT(*) FROM test_table WHERE material = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if _, err := r.Count64(nil); err != nil {
+		t.Fatalf("Error calling Count64: %s", err)
+	}
+	expect = "SELECT COUNT(*) FROM test_table"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+}
+
+func TestDeleteCascade_CompositeKey(t *testing.T) {
+	stool := newStool()
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	if err := r.DeleteCascade(Relation{Table: "legs", Column: "stool_id"}); err == nil {
+		t.Error("Expected DeleteCascade to refuse a composite primary key")
 	}
+	if db.LastExecSql != "" {
+		t.Errorf("Expected no statements, got %s", db.LastExecSql)
+	}
+}
 
-	expect := "DELETE FROM test_table WHERE .* AND .*"
-	if ok, _ := regexp.MatchString(expect, db.LastExecSql); !ok {
-		t.Errorf("Unexpect query: %s", db.LastExecSql)
+func TestFitsColumn(t *testing.T) {
+	var n int
+	var ts time.Time
+	var b sql.NullBool
+	var s *string
+
+	fits := []struct {
+		v        interface{}
+		dataType string
+	}{
+		{n, "integer"},
+		{n, "bigint"},
+		{ts, "timestamp with time zone"},
+		{b, "text"},
+		{s, "character varying"},
 	}
-	if got := db.LastExecArgs[0].(int); got != 1 {
-		t.Errorf("Expected 1, got %d", got)
+	for _, f := range fits {
+		if !fitsColumn(reflect.TypeOf(f.v), f.dataType) {
+			t.Errorf("Expected %T to fit %s", f.v, f.dataType)
+		}
+	}
+
+	if fitsColumn(reflect.TypeOf(n), "text") {
+		t.Error("Expected int not to fit text")
+	}
+	if fitsColumn(reflect.TypeOf(ts), "integer") {
+		t.Error("Expected time.Time not to fit integer")
 	}
 }
 
@@ -286,6 +1537,236 @@ func TestExists(t *testing.T) {
 	if db.LastQueryRowSql != expect {
 		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
 	}
+
+	has, err := r.ExistsWhere(squirrel.Eq{"material": "Wood"})
+	if err != nil || !has {
+		t.Errorf("Expected a match from ExistsWhere, got %t, %v", has, err)
+	}
+	expect = "SELECT 1 FROM test_table WHERE material = ? LIMIT 1"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	New(db, "oracle").Bind("test_table", stool).ExistsWhere(squirrel.Eq{"material": "Wood"})
+	expect = "SELECT 1 FROM test_table WHERE material = :1 AND ROWNUM = 1"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	stool := newStool()
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	if _, err := r.Aggregate("sum", "number_of_legs", squirrel.Eq{"material": "Wood"}); err != nil {
+		t.Errorf("Error calling Aggregate: %s", err)
+	}
+	expect := "SELECT SUM(number_of_legs) FROM test_table WHERE material = ?"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if _, err := r.Aggregate("MAX", "number_of_legs", nil); err != nil {
+		t.Errorf("Error calling Aggregate: %s", err)
+	}
+	expect = "SELECT MAX(number_of_legs) FROM test_table"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	p := New(db, "mysql").Bind("posts", &Post{})
+	if _, err := p.Aggregate("MAX", "id", "title = ? OR title = ?", "a", "b"); err != nil {
+		t.Errorf("Error calling Aggregate: %s", err)
+	}
+	expect = "SELECT MAX(id) FROM posts WHERE (title = ? OR title = ?) AND deleted_at IS NULL"
+	if db.LastQueryRowSql != expect {
+		t.Errorf("Unexpected SQL: expected %q, got %q", expect, db.LastQueryRowSql)
+	}
+
+	if _, err := r.Aggregate("COUNT", "number_of_legs", nil); err == nil {
+		t.Error("Expected an error for an unsupported function")
+	}
+	if _, err := r.Aggregate("SUM", "legs; DROP TABLE test_table", nil); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestSumMinMaxAvg(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	fns := map[string]func(string, interface{}) (float64, error){
+		"SUM": r.Sum, "MIN": r.Min, "MAX": r.Max, "AVG": r.Avg,
+	}
+	for name, fn := range fns {
+		if _, err := fn("number_of_legs", squirrel.Eq{"material": "Wood"}); err != nil {
+			t.Errorf("Error calling %s: %s", name, err)
+		}
+		expect := "SELECT " + name + "(number_of_legs) FROM test_table WHERE material = ?"
+		if db.LastQueryRowSql != expect {
+			t.Errorf("Expected %q, got %q", expect, db.LastQueryRowSql)
+		}
+		if _, err := fn("nope", nil); err == nil {
+			t.Errorf("Expected %s of an unknown column to fail", name)
+		}
+	}
+}
+
+func TestPing(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql")
+
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Error calling Ping: %s", err)
+	}
+	if db.LastQueryRowSql != "SELECT 1" {
+		t.Errorf("Unexpected SQL: %s", db.LastQueryRowSql)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Ping(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestVerifyBinding(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	// The stub has no rows to compare, so it finds no mismatches.
+	if err := r.VerifyBinding(); err != nil {
+		t.Errorf("VerifyBinding error: %s", err)
+	}
+	expect := "SELECT column_name, data_type FROM INFORMATION_SCHEMA.COLUMNS WHERE (table_schema = DATABASE() AND table_name = ?)"
+	if db.LastQuerySql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastQuerySql)
+	}
+}
+
+func TestContextMethods(t *testing.T) {
+	db := &DBStub{}
+	stool := newStool()
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.LoadContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from LoadContext, got %v", err)
+	}
+	if _, err := r.ExistsContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from ExistsContext, got %v", err)
+	}
+	if err := r.InsertContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from InsertContext, got %v", err)
+	}
+	if err := r.UpdateContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from UpdateContext, got %v", err)
+	}
+	if err := r.DeleteContext(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from DeleteContext, got %v", err)
+	}
+	if db.LastQueryRowSql != "" || db.LastExecSql != "" {
+		t.Errorf("No statement should run with a done context, ran %q and %q", db.LastQueryRowSql, db.LastExecSql)
+	}
+
+	if err := r.DeleteContext(context.Background()); err != nil {
+		t.Errorf("Error calling DeleteContext: %s", err)
+	}
+	expect := "DELETE FROM test_table WHERE id = ? AND id_two = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastExecSql)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	db := &DBStub{}
+	stool := newStool()
+	r := New(db, "mysql").Bind("test_table", stool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rc := r.WithContext(ctx)
+	if err := rc.Load(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Load, got %v", err)
+	}
+	if _, err := rc.Exists(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Exists, got %v", err)
+	}
+	if err := rc.Insert(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Insert, got %v", err)
+	}
+	if err := rc.Update(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Update, got %v", err)
+	}
+	if err := rc.Delete(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Delete, got %v", err)
+	}
+	if err := rc.ForceDelete(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from ForceDelete, got %v", err)
+	}
+	if err := rc.UpdateColumns("material"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from UpdateColumns, got %v", err)
+	}
+	if _, err := rc.UpdateIf(squirrel.Eq{"material": "Wood"}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from UpdateIf, got %v", err)
+	}
+	if _, err := rc.UpdateWhere(map[string]interface{}{"material": "Wood"}, "id > 3"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from UpdateWhere, got %v", err)
+	}
+	if _, err := rc.DeleteWhere("id > 3"); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from DeleteWhere, got %v", err)
+	}
+	if err := rc.Upsert(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Upsert, got %v", err)
+	}
+	if err := rc.LoadWhere("id = ?", 1); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from LoadWhere, got %v", err)
+	}
+	if _, err := rc.List(nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from List, got %v", err)
+	}
+	if _, err := rc.ExistsWhere("id = ?", 1); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from ExistsWhere, got %v", err)
+	}
+	if _, err := rc.Aggregate("SUM", "number_of_legs", nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Aggregate, got %v", err)
+	}
+	if _, err := rc.Count(nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Count, got %v", err)
+	}
+	if rc.Interface() != stool {
+		t.Errorf("Expected the copy to be bound to the same record")
+	}
+
+	// The original recorder keeps running without a context.
+	if err := r.Delete(); err != nil {
+		t.Errorf("Error calling Delete: %s", err)
+	}
+	expect := "DELETE FROM test_table WHERE id = ? AND id_two = ?"
+	if db.LastExecSql != expect {
+		t.Errorf("Expected '%s', got '%s'", expect, db.LastExecSql)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	db := &DBStub{}
+	r := New(db, "mysql").Bind("test_table", newStool())
+
+	if err := r.WithTimeout(time.Nanosecond).Load(); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded from Load, got %v", err)
+	}
+	if err := r.WithTimeout(time.Hour).Update(); err != nil {
+		t.Errorf("Error calling Update: %s", err)
+	}
+
+	// The timeout is derived from the context of WithContext.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.WithContext(ctx).WithTimeout(time.Hour).Delete(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled from Delete, got %v", err)
+	}
 }
 
 func TestActiveRecord(t *testing.T) {
@@ -353,6 +1834,21 @@ func (s *DBStub) Begin() (*sql.Tx, error) {
 	return nil, nil
 }
 
+// SlowDBStub is a DBStub whose rows take a while to query.
+type SlowDBStub struct {
+	*DBStub
+	delay time.Duration
+}
+
+func (s *SlowDBStub) QueryRow(query string, args ...interface{}) squirrel.RowScanner {
+	time.Sleep(s.delay)
+	return s.DBStub.QueryRow(query, args...)
+}
+
+func (s *SlowDBStub) QueryRowContext(ctx context.Context, query string, args ...interface{}) squirrel.RowScanner {
+	return s.QueryRow(query, args...)
+}
+
 type RowStub struct {
 	Scanned bool
 }